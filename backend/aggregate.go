@@ -0,0 +1,371 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+)
+
+// Salary aggregation methods accepted by the salaryMethod parameter. They
+// control how the percentile wages of several matching rows are combined into
+// a single SalaryInfo.
+const (
+	// salaryMethodWeighted averages each percentile weighted by row employment
+	salaryMethodWeighted = "weighted"
+	// salaryMethodMixture pools the interpolated per-row wage distributions,
+	// weighted by employment, and reads the percentiles off the mixture
+	salaryMethodMixture = "mixture"
+	// salaryMethodAverage is the original unweighted AVG across rows
+	salaryMethodAverage = "average"
+)
+
+// salaryMethods lists the accepted salaryMethod values, default first
+var salaryMethods = []string{salaryMethodWeighted, salaryMethodMixture, salaryMethodAverage}
+
+// hoursPerYear converts hourly wages to annual ones, as OEWS does for
+// occupations that publish both
+const hoursPerYear = 2080
+
+// maxMinHourly bounds the minHourly filter so its annual equivalent always
+// fits in the int MinSalary, far above any published wage
+const maxMinHourly = 100000
+
+// aggregateRows computes the calculation figures from rows that satisfy every
+// filter except the salary threshold.
+//
+// The legacy count keeps buildQuery's inclusive salary semantics: a row counts
+// in full when its median, 75th or 90th percentile wage reaches MinSalary. The
+// estimated count instead weights each row's employment by the share of its
+// workers expected to earn at least MinSalary (see shareEarningAtLeast). Wages
+// are combined over the legacy-qualifying rows using filters.SalaryMethod.
+// Annual wages a row only publishes hourly are derived first (see
+// deriveAnnualWages).
+func aggregateRows(rows []careerRow, filters Filters) JobAggregate {
+	threshold := float64(filters.MinSalary)
+	var qualifying []careerRow
+	var matching, estimated nullAccumulator
+	annualFromHourly := false
+	for _, r := range rows {
+		r, derived := deriveAnnualWages(r)
+		if filters.MinSalary <= 0 || rowMeetsMinSalary(r, threshold) {
+			annualFromHourly = annualFromHourly || derived
+			qualifying = append(qualifying, r)
+			matching.add(r.TotEmp)
+		}
+		if !r.TotEmp.Valid {
+			continue
+		}
+		share := 1.0
+		if filters.MinSalary > 0 {
+			share = shareEarningAtLeast(r, threshold)
+		}
+		estimated.add(sql.NullFloat64{Float64: r.TotEmp.Float64 * share, Valid: true})
+	}
+
+	agg := combineSalaries(qualifying, filters.SalaryMethod)
+	if len(qualifying) > 0 {
+		agg.WageStatus = wageStatuses(qualifying, [5]sql.NullFloat64{agg.Pct10, agg.Pct25, agg.Median, agg.Pct75, agg.Pct90})
+	}
+	agg.MatchingJobs = matching.sum()
+	agg.TotalEmp = matching.sum()
+	agg.EstimatedMatchingJobs = estimated.sum()
+	agg.AnnualFromHourly = annualFromHourly
+	hourly := combineSalaries(hourlyRows(qualifying), filters.SalaryMethod)
+	agg.HourlyMedian, agg.HourlyPct10, agg.HourlyPct25 = hourly.Median, hourly.Pct10, hourly.Pct25
+	agg.HourlyPct75, agg.HourlyPct90 = hourly.Pct75, hourly.Pct90
+	return agg
+}
+
+// deriveAnnualWages fills each missing annual percentile of r from its hourly
+// counterpart × hoursPerYear, carrying over its top-code flag. derived
+// reports whether any value was filled.
+func deriveAnnualWages(r careerRow) (careerRow, bool) {
+	derived := false
+	annual := [5]*sql.NullFloat64{&r.Pct10, &r.Pct25, &r.Median, &r.Pct75, &r.Pct90}
+	for i, hourly := range rowHourlyPercentiles(r) {
+		if !annual[i].Valid && hourly.Valid {
+			*annual[i] = sql.NullFloat64{Float64: hourly.Float64 * hoursPerYear, Valid: true}
+			r.TopCoded |= r.HTopCoded & (1 << i)
+			derived = true
+		}
+	}
+	return r, derived
+}
+
+// hourlyRows returns copies of rows with the hourly percentiles in the annual
+// fields, so the salary methods can combine hourly wages as well
+func hourlyRows(rows []careerRow) []careerRow {
+	hourly := make([]careerRow, len(rows))
+	for i, r := range rows {
+		hourly[i] = careerRow{TotEmp: r.TotEmp, Median: r.HMedian, Pct10: r.HPct10, Pct25: r.HPct25, Pct75: r.HPct75, Pct90: r.HPct90}
+	}
+	return hourly
+}
+
+// aggregateByYear groups rows by data year and aggregates each year, oldest first
+func aggregateByYear(rows []careerRow, filters Filters) []YearAggregate {
+	byYear := make(map[int][]careerRow)
+	var years []int
+	for _, r := range rows {
+		if _, ok := byYear[r.Year]; !ok {
+			years = append(years, r.Year)
+		}
+		byYear[r.Year] = append(byYear[r.Year], r)
+	}
+	sort.Ints(years)
+
+	aggs := make([]YearAggregate, 0, len(years))
+	for _, y := range years {
+		aggs = append(aggs, YearAggregate{Year: y, JobAggregate: aggregateRows(byYear[y], filters)})
+	}
+	return aggs
+}
+
+// combineSalaries fills the percentile wages of a JobAggregate from rows using
+// the given method (an empty method means salaryMethodWeighted)
+func combineSalaries(rows []careerRow, method string) JobAggregate {
+	if method == "" {
+		method = salaryMethodWeighted
+	}
+	var pcts [5]sql.NullFloat64
+	switch method {
+	case salaryMethodAverage:
+		pcts = averagePercentiles(rows)
+	case salaryMethodMixture:
+		pcts = mixturePercentiles(rows)
+	default:
+		pcts = weightedPercentiles(rows)
+	}
+	return JobAggregate{
+		SalaryMethod: method,
+		Pct10:        pcts[0],
+		Pct25:        pcts[1],
+		Median:       pcts[2],
+		Pct75:        pcts[3],
+		Pct90:        pcts[4],
+	}
+}
+
+// percentileLevels are the cumulative shares of the published OEWS wage
+// percentiles, in the order returned by rowPercentiles
+var percentileLevels = [5]float64{0.10, 0.25, 0.50, 0.75, 0.90}
+
+// rowPercentiles returns a row's 10/25/50/75/90 percentile wages in order
+func rowPercentiles(r careerRow) [5]sql.NullFloat64 {
+	return [5]sql.NullFloat64{r.Pct10, r.Pct25, r.Median, r.Pct75, r.Pct90}
+}
+
+// rowHourlyPercentiles returns a row's hourly percentiles in rowPercentiles order
+func rowHourlyPercentiles(r careerRow) [5]sql.NullFloat64 {
+	return [5]sql.NullFloat64{r.HPct10, r.HPct25, r.HMedian, r.HPct75, r.HPct90}
+}
+
+// scaleWages returns r with every wage multiplied by factor, for the
+// cost-of-living and inflation adjustments
+func scaleWages(r careerRow, factor float64) careerRow {
+	scale := func(v sql.NullFloat64) sql.NullFloat64 {
+		if v.Valid {
+			v.Float64 *= factor
+		}
+		return v
+	}
+	r.Median, r.Pct10, r.Pct25, r.Pct75, r.Pct90 = scale(r.Median), scale(r.Pct10), scale(r.Pct25), scale(r.Pct75), scale(r.Pct90)
+	r.HMedian, r.HPct10, r.HPct25, r.HPct75, r.HPct90 = scale(r.HMedian), scale(r.HPct10), scale(r.HPct25), scale(r.HPct75), scale(r.HPct90)
+	return r
+}
+
+// averagePercentiles reproduces the original AVG(a_*) select list: every row
+// with a value counts equally regardless of its employment
+func averagePercentiles(rows []careerRow) [5]sql.NullFloat64 {
+	var acc [5]nullAccumulator
+	for _, r := range rows {
+		for i, v := range rowPercentiles(r) {
+			acc[i].add(v)
+		}
+	}
+	var pcts [5]sql.NullFloat64
+	for i := range acc {
+		pcts[i] = acc[i].avg()
+	}
+	return pcts
+}
+
+// weightedPercentiles averages each percentile weighted by tot_emp, over the
+// rows that publish both the wage and employment. A percentile whose rows all
+// have suppressed employment falls back to the unweighted average.
+func weightedPercentiles(rows []careerRow) [5]sql.NullFloat64 {
+	var total, weight [5]float64
+	for _, r := range rows {
+		if !r.TotEmp.Valid || r.TotEmp.Float64 <= 0 {
+			continue
+		}
+		for i, v := range rowPercentiles(r) {
+			if v.Valid {
+				total[i] += v.Float64 * r.TotEmp.Float64
+				weight[i] += r.TotEmp.Float64
+			}
+		}
+	}
+	pcts := averagePercentiles(rows)
+	for i := range pcts {
+		if weight[i] > 0 {
+			pcts[i] = sql.NullFloat64{Float64: total[i] / weight[i], Valid: true}
+		}
+	}
+	return pcts
+}
+
+// mixturePercentiles treats the matching jobs as one population: each row
+// contributes its interpolated wage distribution (see wageDistribution)
+// weighted by tot_emp, and the 10/25/50/75/90 percentiles are solved on the
+// pooled CDF. Without any rows publishing both employment and wages it falls
+// back to weightedPercentiles.
+func mixturePercentiles(rows []careerRow) [5]sql.NullFloat64 {
+	var dists []wageDistribution
+	var weights []float64
+	var totalWeight, maxWage float64
+	for _, r := range rows {
+		dist, ok := newWageDistribution(r)
+		if !ok || !r.TotEmp.Valid || r.TotEmp.Float64 <= 0 {
+			continue
+		}
+		dists = append(dists, dist)
+		weights = append(weights, r.TotEmp.Float64)
+		totalWeight += r.TotEmp.Float64
+		if w := dist.topWage(); w > maxWage {
+			maxWage = w
+		}
+	}
+	if len(dists) == 0 {
+		return weightedPercentiles(rows)
+	}
+
+	mixtureCDF := func(wage float64) float64 {
+		var cdf float64
+		for i, d := range dists {
+			cdf += weights[i] * d.cdf(wage)
+		}
+		return cdf / totalWeight
+	}
+	var pcts [5]sql.NullFloat64
+	for i, level := range percentileLevels {
+		// The mixture CDF is continuous and non-decreasing, so bisect for the wage
+		lo, hi := 0.0, maxWage
+		for iter := 0; iter < 64 && hi-lo > 0.01; iter++ {
+			mid := (lo + hi) / 2
+			if mixtureCDF(mid) < level {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		pcts[i] = sql.NullFloat64{Float64: (lo + hi) / 2, Valid: true}
+	}
+	return pcts
+}
+
+// rowMeetsMinSalary is the inclusive salary test of buildQuery: any of the
+// median, 75th or 90th percentile wages reaching the threshold qualifies
+func rowMeetsMinSalary(r careerRow, threshold float64) bool {
+	return atLeast(r.Median, threshold) || atLeast(r.Pct75, threshold) || atLeast(r.Pct90, threshold)
+}
+
+// shareEarningAtLeast estimates the fraction of a row's workers earning at
+// least threshold from the row's wageDistribution. Rows without any wage data
+// contribute nothing.
+func shareEarningAtLeast(r careerRow, threshold float64) float64 {
+	dist, ok := newWageDistribution(r)
+	if !ok {
+		return 0
+	}
+	return 1 - dist.cdf(threshold)
+}
+
+// wageDistribution interpolates the published 10/25/50/75/90 percentile wages
+// of a row. It is modelled as a piecewise-linear CDF through ($0, 0%) and each
+// available percentile point; above the highest published point the last
+// segment's slope is extended until it reaches 100%.
+type wageDistribution struct {
+	knots []wageKnot
+}
+
+type wageKnot struct{ wage, cdf float64 }
+
+// newWageDistribution builds the distribution for r, reporting false when the
+// row publishes no wage percentiles
+func newWageDistribution(r careerRow) (wageDistribution, bool) {
+	knots := []wageKnot{{0, 0}}
+	for i, v := range rowPercentiles(r) {
+		if !v.Valid {
+			continue
+		}
+		level := percentileLevels[i]
+		last := &knots[len(knots)-1]
+		if v.Float64 <= last.wage {
+			// Tied (e.g. capped) percentiles: the CDF at that wage is at least the higher percentile
+			if level > last.cdf && v.Float64 == last.wage {
+				last.cdf = level
+			}
+			continue
+		}
+		knots = append(knots, wageKnot{v.Float64, level})
+	}
+	return wageDistribution{knots: knots}, len(knots) > 1
+}
+
+// cdf returns the estimated share of workers earning less than wage
+func (d wageDistribution) cdf(wage float64) float64 {
+	if wage <= 0 {
+		return 0
+	}
+	knots := d.knots
+	for i := 1; i < len(knots); i++ {
+		lo, hi := knots[i-1], knots[i]
+		if wage <= hi.wage {
+			return lo.cdf + (wage-lo.wage)/(hi.wage-lo.wage)*(hi.cdf-lo.cdf)
+		}
+	}
+	lo, hi := knots[len(knots)-2], knots[len(knots)-1]
+	slope := (hi.cdf - lo.cdf) / (hi.wage - lo.wage)
+	return math.Min(1, hi.cdf+slope*(wage-hi.wage))
+}
+
+// topWage returns the wage at which the extrapolated CDF reaches 100%
+func (d wageDistribution) topWage() float64 {
+	knots := d.knots
+	lo, hi := knots[len(knots)-2], knots[len(knots)-1]
+	if hi.cdf >= 1 {
+		return hi.wage
+	}
+	slope := (hi.cdf - lo.cdf) / (hi.wage - lo.wage)
+	return hi.wage + (1-hi.cdf)/slope
+}
+
+// nullAccumulator reproduces SQL SUM/AVG over nullable values
+type nullAccumulator struct {
+	total float64
+	count int
+}
+
+func (a *nullAccumulator) add(v sql.NullFloat64) {
+	if v.Valid {
+		a.total += v.Float64
+		a.count++
+	}
+}
+
+func (a nullAccumulator) sum() sql.NullFloat64 {
+	return sql.NullFloat64{Float64: a.total, Valid: a.count > 0}
+}
+
+func (a nullAccumulator) avg() sql.NullFloat64 {
+	if a.count == 0 {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: a.total / float64(a.count), Valid: true}
+}
+
+// atLeast reports whether a nullable value is present and >= threshold
+func atLeast(v sql.NullFloat64, threshold float64) bool {
+	return v.Valid && v.Float64 >= threshold
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"database/sql"
+	"math"
+	"testing"
+)
+
+func wage(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} }
+
+func TestShareEarningAtLeastInterpolatesPercentiles(t *testing.T) {
+	row := careerRow{Pct10: wage(50000), Pct25: wage(60000), Median: wage(70000), Pct75: wage(80000), Pct90: wage(100000)}
+	medianOnly := careerRow{Median: wage(70000)}
+
+	cases := []struct {
+		name      string
+		row       careerRow
+		threshold float64
+		want      float64
+	}{
+		{"at median", row, 70000, 0.50},
+		{"between p75 and p90", row, 90000, 0.175},
+		{"at p90", row, 100000, 0.10},
+		{"extrapolated above p90", row, 110000, 0.025},
+		{"far above p90", row, 200000, 0},
+		{"below p10 from zero", row, 25000, 0.95},
+		{"median only below", medianOnly, 35000, 0.75},
+		{"median only far above", medianOnly, 140000, 0},
+		{"no wage data", careerRow{TotEmp: wage(100)}, 50000, 0},
+	}
+	for _, c := range cases {
+		if got := shareEarningAtLeast(c.row, c.threshold); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+}
+
+func TestAggregateRowsReturnsLegacyAndEstimatedCounts(t *testing.T) {
+	rows := []careerRow{
+		// Only the top 10% clears $100k, yet the legacy filter counts all 1000
+		{TotEmp: wage(1000), Pct10: wage(50000), Pct25: wage(60000), Median: wage(70000), Pct75: wage(80000), Pct90: wage(100000)},
+		// Below the threshold at every percentile
+		{TotEmp: wage(500), Median: wage(40000), Pct75: wage(45000), Pct90: wage(50000)},
+	}
+
+	agg := aggregateRows(rows, Filters{MinSalary: 100000})
+	if agg.MatchingJobs.Float64 != 1000 {
+		t.Errorf("expected legacy count 1000, got %v", agg.MatchingJobs.Float64)
+	}
+	if math.Abs(agg.EstimatedMatchingJobs.Float64-100) > 1e-9 {
+		t.Errorf("expected estimated count 100, got %v", agg.EstimatedMatchingJobs.Float64)
+	}
+
+	// Without a threshold both counts cover all employment
+	agg = aggregateRows(rows, Filters{})
+	if agg.MatchingJobs.Float64 != 1500 || agg.EstimatedMatchingJobs.Float64 != 1500 {
+		t.Errorf("expected 1500/1500, got %v/%v", agg.MatchingJobs.Float64, agg.EstimatedMatchingJobs.Float64)
+	}
+
+	if agg = aggregateRows(nil, Filters{MinSalary: 50000}); agg.MatchingJobs.Valid || agg.EstimatedMatchingJobs.Valid {
+		t.Errorf("expected NULL aggregates for no rows, got %+v", agg)
+	}
+}
+
+func TestCombineSalariesMethods(t *testing.T) {
+	rows := []careerRow{
+		{TotEmp: wage(40000), Pct10: wage(30000), Pct25: wage(35000), Median: wage(40000), Pct75: wage(45000), Pct90: wage(50000)},
+		{TotEmp: wage(40), Pct10: wage(130000), Pct25: wage(135000), Median: wage(140000), Pct75: wage(145000), Pct90: wage(150000)},
+	}
+
+	avg := combineSalaries(rows, salaryMethodAverage)
+	if avg.Median.Float64 != 90000 || avg.SalaryMethod != salaryMethodAverage {
+		t.Errorf("expected plain average median 90000, got %v (%s)", avg.Median.Float64, avg.SalaryMethod)
+	}
+
+	weighted := combineSalaries(rows, "")
+	if want := (40000.0*40000 + 140000*40) / 40040; math.Abs(weighted.Median.Float64-want) > 1e-6 || weighted.SalaryMethod != salaryMethodWeighted {
+		t.Errorf("expected weighted median %v, got %v (%s)", want, weighted.Median.Float64, weighted.SalaryMethod)
+	}
+
+	// A single row's mixture reproduces its own percentiles
+	single := combineSalaries(rows[:1], salaryMethodMixture)
+	for name, got := range map[string]sql.NullFloat64{"p10": single.Pct10, "median": single.Median, "p90": single.Pct90} {
+		want := map[string]float64{"p10": 30000, "median": 40000, "p90": 50000}[name]
+		if math.Abs(got.Float64-want) > 1 {
+			t.Errorf("mixture %s: expected %v, got %v", name, want, got.Float64)
+		}
+	}
+	// The small high-paid occupation only moves the pooled top tail
+	mixture := combineSalaries(rows, salaryMethodMixture)
+	if math.Abs(mixture.Median.Float64-40000) > 50 || mixture.Pct90.Float64 > 51000 {
+		t.Errorf("unexpected mixture percentiles: %+v", mixture)
+	}
+}
+
+func TestWeightedPercentilesFallBackWithoutEmployment(t *testing.T) {
+	rows := []careerRow{{Median: wage(50000)}, {Median: wage(70000)}}
+	if got := combineSalaries(rows, salaryMethodWeighted).Median.Float64; got != 60000 {
+		t.Errorf("expected unweighted fallback 60000, got %v", got)
+	}
+	if got := combineSalaries(rows, salaryMethodMixture).Median.Float64; got != 60000 {
+		t.Errorf("expected mixture fallback 60000, got %v", got)
+	}
+}
+
+func TestAggregateRowsDerivesAnnualFromHourly(t *testing.T) {
+	rows := []careerRow{
+		// Actors publish hourly wages only
+		{TotEmp: wage(500), HMedian: wage(25), HPct90: wage(60)},
+		{TotEmp: wage(1500), Median: wage(62400), HMedian: wage(30)},
+	}
+
+	agg := aggregateRows(rows, Filters{})
+	if !agg.AnnualFromHourly || agg.MatchingJobs.Float64 != 2000 {
+		t.Fatalf("expected both rows with derived annual wages, got %+v", agg)
+	}
+	// (25×2080×500 + 62400×1500) / 2000
+	if math.Abs(agg.Median.Float64-59800) > 1e-6 || math.Abs(agg.HourlyMedian.Float64-28.75) > 1e-9 {
+		t.Errorf("unexpected medians: annual %v, hourly %v", agg.Median.Float64, agg.HourlyMedian.Float64)
+	}
+
+	// $100k is reached only by the actors' derived 90th percentile (124,800)
+	agg = aggregateRows(rows, Filters{MinSalary: 100000})
+	if agg.MatchingJobs.Float64 != 500 || !agg.AnnualFromHourly {
+		t.Errorf("expected only the hourly-only row to qualify, got %+v", agg)
+	}
+	if agg = aggregateRows(rows[1:], Filters{}); agg.AnnualFromHourly {
+		t.Errorf("expected no derived wages for a row publishing annual figures")
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Area is one row of the areas dimension table: an OEWS area with its code,
+// type and the USPS abbreviations of the states it lies in
+type Area struct {
+	Code   string   `json:"code,omitempty"`
+	Title  string   `json:"title"`
+	Type   string   `json:"type"`
+	States []string `json:"states,omitempty"`
+}
+
+// oewsAreaTypes maps OEWS AREA_TYPE codes to area types. Territories (3) are
+// listed with the states.
+var oewsAreaTypes = map[string]string{
+	"1": areaTypeNational,
+	"2": areaTypeState,
+	"3": areaTypeState,
+	"4": areaTypeMetro,
+	"6": areaTypeNonmetro,
+}
+
+// newArea builds the metadata of an area from its OEWS AREA, AREA_TYPE and
+// PRIM_STATE values. Data without an AREA_TYPE (older CSVs and databases)
+// falls back to classifyAreaTitle.
+func newArea(code, title, oewsType, primState string) Area {
+	areaType, ok := oewsAreaTypes[oewsType]
+	if !ok {
+		areaType = classifyAreaTitle(title)
+	}
+	return Area{Code: code, Title: title, Type: areaType, States: areaMemberStates(title, areaType, primState)}
+}
+
+// areaMemberStates lists the states an area lies in: a state itself, every
+// state of a metro title's suffix ("Philadelphia-Camden-Wilmington,
+// PA-NJ-DE-MD") and the state named by a nonmetropolitan area title.
+// primState is used when the title names none.
+func areaMemberStates(title, areaType, primState string) []string {
+	var states []string
+	switch areaType {
+	case areaTypeNational:
+		return nil
+	case areaTypeState:
+		if abbr, ok := stateAbbrs[title]; ok {
+			states = []string{abbr}
+		}
+	case areaTypeMetro:
+		if i := strings.LastIndex(title, ", "); i >= 0 {
+			for _, abbr := range strings.Split(title[i+2:], "-") {
+				if isStateAbbr(abbr) && !containsString(states, abbr) {
+					states = append(states, abbr)
+				}
+			}
+		}
+	case areaTypeNonmetro:
+		// The longest suffix wins so "West Virginia" is not read as "Virginia"
+		lower, best := strings.ToLower(title), ""
+		for name, abbr := range stateAbbrs {
+			if strings.HasSuffix(lower, strings.ToLower(name)+" nonmetropolitan area") && len(name) > len(best) {
+				best, states = name, []string{abbr}
+			}
+		}
+	}
+	if len(states) == 0 && primState != "" {
+		states = []string{primState}
+	}
+	return states
+}
+
+// AreasHandler returns the area metadata (code, type, member states) ordered
+// by title, optionally only the areas of one type
+func (h *Handlers) AreasHandler(w http.ResponseWriter, r *http.Request) {
+	areaType := r.URL.Query().Get("type")
+	types := append([]string{areaTypeNational}, rankAreaTypes...)
+	if areaType != "" && !containsString(types, areaType) {
+		http.Error(w, "type must be one of: "+strings.Join(types, ", "), http.StatusBadRequest)
+		return
+	}
+
+	metadata, err := h.store.Areas(r.Context())
+	if err != nil {
+		log.Printf("Error querying areas: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	areas := make([]Area, 0, len(metadata))
+	for _, a := range sortedAreas(metadata) {
+		if areaType == "" || a.Type == areaType {
+			areas = append(areas, a)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"areas": areas,
+		"count": len(areas),
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// isStateAbbr reports whether abbr is a known state or territory abbreviation
+func isStateAbbr(abbr string) bool {
+	_, a, ok := lookupState(abbr)
+	return ok && a == abbr
+}
+
+// areasFromRows collects the area metadata of every distinct area title in
+// rows. The first row of an area supplies its type and primary state; a later
+// row only fills in a missing code.
+func areasFromRows(rowSets ...[]careerRow) map[string]Area {
+	areas := make(map[string]Area)
+	for _, rows := range rowSets {
+		for _, r := range rows {
+			if r.AreaTitle == "" {
+				continue
+			}
+			if a, ok := areas[r.AreaTitle]; !ok {
+				areas[r.AreaTitle] = newArea(r.AreaCode, r.AreaTitle, r.AreaType, r.PrimState)
+			} else if a.Code == "" && r.AreaCode != "" {
+				a.Code = r.AreaCode
+				areas[r.AreaTitle] = a
+			}
+		}
+	}
+	return areas
+}
+
+// areaTypeOf returns the type of an area title from the area metadata, or
+// classifies the title when the area has none
+func areaTypeOf(areas map[string]Area, title string) string {
+	if a, ok := areas[title]; ok {
+		return a.Type
+	}
+	return classifyAreaTitle(title)
+}
+
+// sortedAreas returns the areas ordered by title
+func sortedAreas(areas map[string]Area) []Area {
+	list := make([]Area, 0, len(areas))
+	for _, a := range areas {
+		list = append(list, a)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Title < list[j].Title })
+	return list
+}
+
+// insertAreas upserts area metadata by title within tx. States are stored
+// as a comma-separated list of abbreviations.
+func insertAreas(ctx context.Context, tx *sql.Tx, d dialect, areas []Area) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO areas (area_title, area_code, area_type, states)
+		VALUES (`+d.placeholder(1)+`, `+d.placeholder(2)+`, `+d.placeholder(3)+`, `+d.placeholder(4)+`)
+		ON CONFLICT (area_title) DO UPDATE SET
+			area_code = excluded.area_code,
+			area_type = excluded.area_type,
+			states = excluded.states`)
+	if err != nil {
+		return fmt.Errorf("error preparing areas upsert: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, a := range areas {
+		if _, err := stmt.ExecContext(ctx, a.Title, nullString(a.Code), a.Type, strings.Join(a.States, ",")); err != nil {
+			return fmt.Errorf("error upserting area %s: %v", a.Title, err)
+		}
+	}
+	return nil
+}
+
+// upsertAreas inserts or replaces the metadata of the given areas
+func upsertAreas(ctx context.Context, db *sql.DB, d dialect, areas map[string]Area) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error { return insertAreas(ctx, tx, d, sortedAreas(areas)) })
+}
+
+// backfillAreas seeds the areas table from the titles and codes already in
+// career_data and career_data_history, for databases loaded before the table
+// existed. Re-running ingest replaces the seeded rows with the OEWS AREA_TYPE.
+func backfillAreas(ctx context.Context, tx *sql.Tx, d dialect) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT area_title, area_code FROM career_data
+		UNION
+		SELECT area_title, area_code FROM career_data_history`)
+	if err != nil {
+		return err
+	}
+	var existing []careerRow
+	for rows.Next() {
+		var r careerRow
+		var code sql.NullString
+		if err := rows.Scan(&r.AreaTitle, &code); err != nil {
+			rows.Close()
+			return err
+		}
+		r.AreaCode = code.String
+		existing = append(existing, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return insertAreas(ctx, tx, d, sortedAreas(areasFromRows(existing)))
+}
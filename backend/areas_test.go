@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewAreaMemberStates(t *testing.T) {
+	cases := []struct {
+		code, title, oewsType, primState string
+		want                             Area
+	}{
+		{"99", "U.S.", "1", "US", Area{Code: "99", Title: "U.S.", Type: areaTypeNational}},
+		{"26", "Michigan", "2", "MI", Area{Code: "26", Title: "Michigan", Type: areaTypeState, States: []string{"MI"}}},
+		{"72", "Puerto Rico", "3", "PR", Area{Code: "72", Title: "Puerto Rico", Type: areaTypeState, States: []string{"PR"}}},
+		{"37980", "Philadelphia-Camden-Wilmington, PA-NJ-DE-MD", "4", "PA",
+			Area{Code: "37980", Title: "Philadelphia-Camden-Wilmington, PA-NJ-DE-MD", Type: areaTypeMetro, States: []string{"PA", "NJ", "DE", "MD"}}},
+		{"5400002", "Northern West Virginia nonmetropolitan area", "6", "WV",
+			Area{Code: "5400002", Title: "Northern West Virginia nonmetropolitan area", Type: areaTypeNonmetro, States: []string{"WV"}}},
+		// Without AREA_TYPE the title is classified
+		{"", "Toledo, OH", "", "", Area{Title: "Toledo, OH", Type: areaTypeMetro, States: []string{"OH"}}},
+		{"", "Kansas nonmetropolitan area", "", "", Area{Title: "Kansas nonmetropolitan area", Type: areaTypeNonmetro, States: []string{"KS"}}},
+		// PRIM_STATE fills in when the title names no state
+		{"3100001", "Western Region nonmetropolitan area", "6", "CT", Area{Code: "3100001", Title: "Western Region nonmetropolitan area", Type: areaTypeNonmetro, States: []string{"CT"}}},
+	}
+	for _, tc := range cases {
+		if got := newArea(tc.code, tc.title, tc.oewsType, tc.primState); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: expected %+v, got %+v", tc.title, tc.want, got)
+		}
+	}
+}
+
+func TestBackfillAreasSeedsExistingData(t *testing.T) {
+	ctx := context.Background()
+	lite := newTestSQLiteStore(t)
+	if _, err := lite.db.Exec("DELETE FROM areas"); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := lite.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backfillAreas(ctx, tx, sqliteDialect); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	areas, err := lite.Areas(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(areas) != 5 || areas["U.S."].Type != areaTypeNational || areas["Michigan nonmetropolitan area"].Type != areaTypeNonmetro ||
+		!reflect.DeepEqual(areas["Detroit-Warren-Dearborn, MI"].States, []string{"MI"}) {
+		t.Errorf("unexpected seeded areas %+v", areas)
+	}
+	states, _ := lite.ListStates(ctx)
+	if !reflect.DeepEqual(states, []string{"Michigan"}) {
+		t.Errorf("expected the seeded state, got %v", states)
+	}
+}
+
+func TestAreasHandlerFiltersByType(t *testing.T) {
+	h := NewHandlers(newTestMemoryStore(t))
+	rr := httptest.NewRecorder()
+	h.AreasHandler(rr, httptest.NewRequest("GET", "/api/areas?type=metro", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var body struct {
+		Areas []Area `json:"areas"`
+		Count int    `json:"count"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 2 || body.Areas[0].Title != "Detroit-Warren-Dearborn, MI" || body.Areas[1].Title != "Toledo, OH" {
+		t.Errorf("unexpected metro areas %+v", body)
+	}
+
+	rr = httptest.NewRecorder()
+	h.AreasHandler(rr, httptest.NewRequest("GET", "/api/areas?type=county", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown type, got %d", rr.Code)
+	}
+}
+
+func TestCalculateReportsMatchedArea(t *testing.T) {
+	store := &fakeStore{
+		years: []int{2023},
+		areas: map[string]Area{"Michigan": {Code: "26", Title: "Michigan", Type: areaTypeState, States: []string{"MI"}}},
+	}
+	h := NewHandlers(store)
+
+	// Selected by code without matching rows: the title comes from the areas table
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?areaCode=26&occupation=Astronauts", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var result CalculationResult
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Location != "Michigan" || result.AreaCode != "26" || result.AreaType != areaTypeState || result.Status != statusNoRows {
+		t.Errorf("unexpected area description %+v", result)
+	}
+}
+
+func TestAreasByStateListsMultiStateMetros(t *testing.T) {
+	rows, err := readCareerCSV(strings.NewReader(`AREA_TITLE,OCC_CODE,OCC_TITLE,TOT_EMP,A_MEDIAN
+"Philadelphia-Camden-Wilmington, PA-NJ-DE-MD",29-1141,Registered Nurses,60000,90000
+"Salisbury, MD-DE",29-1141,Registered Nurses,5000,80000
+New Jersey,29-1141,Registered Nurses,80000,95000
+"Trenton-Princeton, NJ",29-1141,Registered Nurses,6000,92000
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := NewMemoryStore(rows)
+	db, err := initSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	if _, _, err := upsertCareerRows(ctx, db, sqliteDialect, careerDataTable, rows); err != nil {
+		t.Fatal(err)
+	}
+	if err := upsertAreas(ctx, db, sqliteDialect, areasFromRows(rows)); err != nil {
+		t.Fatal(err)
+	}
+	lite := NewSQLiteStore(db)
+
+	for state, want := range map[string][]string{
+		"New Jersey": {"New Jersey", "Philadelphia-Camden-Wilmington, PA-NJ-DE-MD", "Trenton-Princeton, NJ"},
+		"Delaware":   {"Philadelphia-Camden-Wilmington, PA-NJ-DE-MD", "Salisbury, MD-DE"},
+		"md":         {"Philadelphia-Camden-Wilmington, PA-NJ-DE-MD", "Salisbury, MD-DE"},
+		"Atlantis":   nil,
+	} {
+		for name, store := range map[string]CareerDataStore{"memory": mem, "sqlite": lite} {
+			if got, _ := store.AreasForState(ctx, state); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s %s: expected %v, got %v", name, state, want, got)
+			}
+		}
+	}
+
+	h := NewHandlers(mem)
+	rr := httptest.NewRecorder()
+	h.AreasByStateHandler(rr, httptest.NewRequest("GET", "/api/areas-by-state?state=DE", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Salisbury, MD-DE") {
+		t.Errorf("expected DE to list Salisbury, got %d: %s", rr.Code, rr.Body)
+	}
+	for _, state := range []string{"Atlantis", "XX", ", GA"} {
+		rr := httptest.NewRecorder()
+		h.AreasByStateHandler(rr, httptest.NewRequest("GET", "/api/areas-by-state?state="+url.QueryEscape(state), nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", state, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CompareLimits bounds the work a single /api/compare request may do
+type CompareLimits struct {
+	MaxLocations int // locations accepted per request
+	Workers      int // calculations run concurrently per request
+}
+
+// defaultCompareLimits applies when COMPARE_MAX_LOCATIONS / COMPARE_WORKERS are unset
+var defaultCompareLimits = CompareLimits{MaxLocations: 10, Workers: 4}
+
+// CompareResult is one location's calculation, ranked by regional percentage
+type CompareResult struct {
+	Rank int `json:"rank"`
+	CalculationResult
+}
+
+// CompareHandler handles the /api/compare endpoint: the same filters run
+// against every repeated location parameter, ranked by percentageRegion
+func (h *Handlers) CompareHandler(w http.ResponseWriter, r *http.Request) {
+	filters, err := parseFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	locations := parseCompareLocations(r.URL.Query()["location"])
+	if len(locations) == 0 {
+		http.Error(w, "Location is required", http.StatusBadRequest)
+		return
+	}
+	if len(locations) > h.compareLimits.MaxLocations {
+		http.Error(w, fmt.Sprintf("at most %d locations may be compared", h.compareLimits.MaxLocations), http.StatusBadRequest)
+		return
+	}
+
+	year, err := h.resolveYear(r.Context(), r.URL.Query().Get("year"))
+	if err != nil {
+		writeError(w, err, "Error resolving data year")
+		return
+	}
+	filters.Year = year
+	filters.AreaCode = ""
+
+	results, err := h.runComparison(r.Context(), filters, locations)
+	if err != nil {
+		writeError(w, err, "Error comparing locations")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+		"year":    year,
+		"count":   len(results),
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// parseCompareLocations trims the location values and drops blanks and duplicates,
+// keeping the request order
+func parseCompareLocations(values []string) []string {
+	seen := make(map[string]bool)
+	var locations []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[strings.ToLower(v)] {
+			continue
+		}
+		seen[strings.ToLower(v)] = true
+		locations = append(locations, v)
+	}
+	return locations
+}
+
+// runComparison runs calculateJobOpportunities for each location on a
+// worker pool of h.compareLimits.Workers goroutines. The first error cancels
+// the remaining work. Results are ranked by regional percentage, highest first.
+func (h *Handlers) runComparison(ctx context.Context, filters Filters, locations []string) ([]CompareResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := h.compareLimits.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(locations) {
+		workers = len(locations)
+	}
+
+	results := make([]CompareResult, len(locations))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range jobs {
+				f := filters
+				f.Location = locations[idx]
+				result, err := h.calculateJobOpportunities(ctx, f)
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("%s: %w", locations[idx], err)
+						cancel()
+					})
+					continue
+				}
+				results[idx] = CompareResult{CalculationResult: *result}
+			}
+		}()
+	}
+
+feed:
+	for i := range locations {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].PercentageRegion > results[j].PercentageRegion
+	})
+	for i := range results {
+		results[i].Rank = i + 1
+	}
+	return results, nil
+}
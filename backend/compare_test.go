@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompareHandlerRanksLocations(t *testing.T) {
+	h := NewHandlers(newTestMemoryStore(t))
+	h.compareLimits = CompareLimits{MaxLocations: 3, Workers: 2}
+
+	rr := httptest.NewRecorder()
+	h.CompareHandler(rr, httptest.NewRequest("GET",
+		"/api/compare?occupation=Registered+Nurses&location=Michigan&location=Toledo,+OH&location=Detroit-Warren-Dearborn,+MI&location=michigan", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var body struct {
+		Results []CompareResult `json:"results"`
+		Year    int             `json:"year"`
+		Count   int             `json:"count"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 3 || body.Year != defaultDataYear {
+		t.Fatalf("expected three de-duplicated locations, got %+v", body)
+	}
+
+	// Both metros are 100% nurses in the fixture; ties keep request order
+	want := []string{"Toledo, OH", "Detroit-Warren-Dearborn, MI", "Michigan"}
+	for i, r := range body.Results {
+		if r.Location != want[i] || r.Rank != i+1 {
+			t.Errorf("rank %d: expected %s, got %s (rank %d)", i+1, want[i], r.Location, r.Rank)
+		}
+	}
+	if m := body.Results[2]; m.MatchingJobs != 100000 || m.TotalJobsRegion != 202000 {
+		t.Errorf("unexpected Michigan figures: %+v", m.CalculationResult)
+	}
+}
+
+func TestCompareHandlerEnforcesLocationLimit(t *testing.T) {
+	h := NewHandlers(newTestMemoryStore(t))
+	h.compareLimits = CompareLimits{MaxLocations: 2, Workers: 1}
+
+	for query, want := range map[string]int{
+		"location=Michigan&location=Toledo,+OH&location=Ohio": http.StatusBadRequest,
+		"occupation=Nurse":                      http.StatusBadRequest,
+		"location=Michigan&location=Toledo,+OH": http.StatusOK,
+	} {
+		rr := httptest.NewRecorder()
+		h.CompareHandler(rr, httptest.NewRequest("GET", "/api/compare?"+query, nil))
+		if rr.Code != want {
+			t.Errorf("%s: expected %d, got %d", query, want, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvTable reads a CSV file whose columns are matched by header name
+type csvTable struct {
+	reader *csv.Reader
+	cols   map[string]int
+	line   int
+}
+
+// csvRecord is one data row of a csvTable
+type csvRecord struct {
+	cells []string
+	cols  map[string]int
+	// line is the 1-based line number, for error messages
+	line int
+}
+
+// newCSVTable reads the header row of r. Records may have any number of
+// fields; missing trailing cells read as "".
+func newCSVTable(r io.Reader) (*csvTable, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading header: %v", err)
+	}
+	return &csvTable{reader: reader, cols: headerIndex(header), line: 1}, nil
+}
+
+// has reports whether the header names col
+func (t *csvTable) has(col string) bool {
+	_, ok := t.cols[col]
+	return ok
+}
+
+// alias reads col from the column named name when the header lacks col
+func (t *csvTable) alias(name, col string) {
+	if i, ok := t.cols[name]; ok && !t.has(col) {
+		t.cols[col] = i
+	}
+}
+
+// require fails on the first of cols missing from the header
+func (t *csvTable) require(cols ...string) error {
+	for _, col := range cols {
+		if !t.has(col) {
+			return fmt.Errorf("missing required column %s", col)
+		}
+	}
+	return nil
+}
+
+// next returns the next record, or io.EOF after the last one
+func (t *csvTable) next() (csvRecord, error) {
+	cells, err := t.reader.Read()
+	t.line++
+	if errors.Is(err, io.EOF) {
+		return csvRecord{}, io.EOF
+	}
+	if err != nil {
+		return csvRecord{}, fmt.Errorf("line %d: %v", t.line, err)
+	}
+	return csvRecord{cells: cells, cols: t.cols, line: t.line}, nil
+}
+
+// text returns the trimmed value of a named column, or "" if absent
+func (r csvRecord) text(col string) string {
+	return cellValue(r.cells, r.cols, col)
+}
+
+// headerIndex maps upper-cased header names to column positions, ignoring a
+// UTF-8 byte order mark before the first name
+func headerIndex(cells []string) map[string]int {
+	cols := make(map[string]int, len(cells))
+	for i, name := range cells {
+		cols[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	return cols
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -8,6 +9,7 @@ import (
 	"strings"
 
 	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
 )
 
 func initDB() (*sql.DB, error) {
@@ -74,6 +76,29 @@ func initDB() (*sql.DB, error) {
 	return db, nil
 }
 
+// initSQLite opens (creating if needed) a SQLite database file and applies
+// pending schema migrations. Use ":memory:" for a throwaway database.
+func initSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite database: %v", err)
+	}
+	if path == ":memory:" {
+		// Every connection to :memory: is a separate database
+		db.SetMaxOpenConns(1)
+	}
+	if err = db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error connecting to sqlite database: %v", err)
+	}
+	if err = migrate(context.Background(), db, sqliteDialect); err != nil {
+		db.Close()
+		return nil, err
+	}
+	log.Printf("Successfully opened sqlite database %s", path)
+	return db, nil
+}
+
 // getEnv function is defined in main.go
 
 // ensureSSLModeInURL appends sslmode=require to Postgres URLs that do not already specify sslmode
@@ -0,0 +1,37 @@
+package main
+
+import "fmt"
+
+// dialect captures the SQL differences between the supported databases so
+// query construction can target Postgres and SQLite from the same code
+type dialect struct {
+	name string
+	// ilike is the case-insensitive LIKE operator
+	ilike string
+	// placeholderPrefix precedes the 1-based argument number ($1 or ?1)
+	placeholderPrefix string
+	// autoIncrementPK is the column definition for a surrogate integer key
+	autoIncrementPK string
+}
+
+var (
+	postgresDialect = dialect{
+		name:              "postgres",
+		ilike:             "ILIKE",
+		placeholderPrefix: "$",
+		autoIncrementPK:   "SERIAL PRIMARY KEY",
+	}
+	// SQLite's LIKE is case-insensitive for ASCII, and ?NNN placeholders may be
+	// reused within a statement just like Postgres' $N
+	sqliteDialect = dialect{
+		name:              "sqlite",
+		ilike:             "LIKE",
+		placeholderPrefix: "?",
+		autoIncrementPK:   "INTEGER PRIMARY KEY AUTOINCREMENT",
+	}
+)
+
+// placeholder returns the bind parameter for the n-th (1-based) argument
+func (d dialect) placeholder(n int) string {
+	return fmt.Sprintf("%s%d", d.placeholderPrefix, n)
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -15,8 +18,87 @@ type Filters struct {
 	Location   string `json:"location"`
 	Occupation string `json:"occupation"`
 	MinSalary  int    `json:"minSalary"`
-	Education  string `json:"education"`
-	Experience string `json:"experience"`
+	// MinHourly is the minHourly parameter; parseCriteria converts it into
+	// MinSalary (× hoursPerYear) so both thresholds share one salary test
+	MinHourly  float64 `json:"minHourly,omitempty"`
+	Education  string  `json:"education"`
+	Experience string  `json:"experience"`
+	Year       int     `json:"year"`
+	// OccupationMatch is how Occupation is compared with occ_title
+	// (matchContains, matchExact or matchPrefix)
+	OccupationMatch string `json:"match"`
+	// OccCode matches occ_code exactly, e.g. "29-1141"
+	OccCode string `json:"occCode"`
+	// OccGroup selects every occupation in a SOC major, minor or broad group,
+	// e.g. "15-0000" for Computer and Mathematical Occupations
+	OccGroup string `json:"occGroup"`
+	// LocationMatch is how Location is compared with area_title; empty means
+	// matchExact so the numerator covers exactly the denominator's areas
+	LocationMatch string `json:"locationMatch"`
+	// AreaCode matches the OEWS area code exactly, e.g. "26" or "19820"
+	AreaCode string `json:"areaCode"`
+	// Areas restricts the location to these exact area titles, e.g. the
+	// areas of a radiusMiles search
+	Areas []string `json:"areas,omitempty"`
+	// Scope is the calculation scope (scopeArea, scopeState or scopeNational)
+	Scope string `json:"scope,omitempty"`
+	// Occupations holds each selection when several occupations are
+	// requested at once; Occupation and OccCode are empty in that case
+	Occupations []OccupationSelection `json:"occupations,omitempty"`
+	// SalaryMethod selects how wages are combined across matching rows
+	// (salaryMethodWeighted, salaryMethodMixture or salaryMethodAverage)
+	SalaryMethod string `json:"salaryMethod"`
+	// Adjust deflates wages before the salary test and aggregation
+	// (adjustNone or adjustRPP)
+	Adjust string `json:"adjust,omitempty"`
+	// InflationTo restates every wage in that year's dollars using the CPI
+	// index; 0 keeps nominal wages
+	InflationTo int `json:"inflationTo,omitempty"`
+}
+
+// OccupationSelection is one requested occupation: a title (matched with
+// OccupationMatch), an exact code, or both
+type OccupationSelection struct {
+	Occupation string `json:"occupation,omitempty"`
+	OccCode    string `json:"occCode,omitempty"`
+}
+
+// maxOccupationSelections bounds the store queries a single request may fan out to
+const maxOccupationSelections = 10
+
+// selectionFilters expands filters into one Filters per requested occupation
+func (f Filters) selectionFilters() []Filters {
+	if len(f.Occupations) == 0 {
+		return []Filters{f}
+	}
+	expanded := make([]Filters, 0, len(f.Occupations))
+	for _, s := range f.Occupations {
+		single := f
+		single.Occupations = nil
+		single.Occupation, single.OccCode = s.Occupation, s.OccCode
+		expanded = append(expanded, single)
+	}
+	return expanded
+}
+
+// hasOccupation reports whether the filters select occupations by title,
+// code or SOC group
+func (f Filters) hasOccupation() bool {
+	return f.Occupation != "" || f.OccCode != "" || f.OccGroup != "" || len(f.Occupations) > 0
+}
+
+// locationMatchMode returns LocationMatch, defaulting to exact area matching
+func (f Filters) locationMatchMode() string {
+	if f.LocationMatch == "" {
+		return matchExact
+	}
+	return f.LocationMatch
+}
+
+// regionScope keeps only the filters that select areas, for the regional
+// denominator
+func (f Filters) regionScope() Filters {
+	return Filters{Location: f.Location, LocationMatch: f.LocationMatch, AreaCode: f.AreaCode, Areas: f.Areas, Year: f.Year}
 }
 
 // CalculationResult represents the response data
@@ -27,53 +109,175 @@ type CalculationResult struct {
 	TotalJobs        int        `json:"totalJobs"`
 	TotalJobsRegion  int        `json:"totalJobsRegion"`
 	Location         string     `json:"location"`
+	Year             int        `json:"year"`
 	MinSalaryMet     bool       `json:"minSalaryMet"`
 	SalaryInfo       SalaryInfo `json:"salaryInfo"`
+	HourlyInfo       HourlyInfo `json:"hourlyInfo"`
+
+	// Status tells a genuine zero from a selection without data: zero counts
+	// and a false MinSalaryMet mean "too low" only when it is statusMatched
+	Status string `json:"status"`
+	// Scope echoes the calculation scope
+	Scope string `json:"scope,omitempty"`
+	// AreaCode and AreaType describe the matched area from the areas table
+	// when the result covers a single area
+	AreaCode string `json:"areaCode,omitempty"`
+	AreaType string `json:"areaType,omitempty"`
+	// Radius lists the areas a radiusMiles search summed; Location is empty
+	// when it included several
+	Radius *RadiusSearch `json:"radius,omitempty"`
+
+	// Estimated* count only the share of each occupation's workers expected to
+	// earn at least minSalary, interpolated from the wage percentiles
+	EstimatedMatchingJobs     int     `json:"estimatedMatchingJobs"`
+	EstimatedPercentage       float64 `json:"estimatedPercentage"`
+	EstimatedPercentageRegion float64 `json:"estimatedPercentageRegion"`
+
+	// Breakdown reports each occupation separately when several were
+	// requested; the fields above then cover their combined rows
+	Breakdown []OccupationResult `json:"breakdown,omitempty"`
+
+	// Adjust echoes the wage adjustment; with adjust=rpp every wage above and
+	// the minSalary test are in national-average dollars, and PriceParities
+	// lists the parity applied per area (areas without one stay nominal)
+	Adjust        string             `json:"adjust,omitempty"`
+	PriceParities map[string]float64 `json:"priceParities,omitempty"`
+	// InflationTo echoes the year whose dollars every wage above and the
+	// minSalary test are expressed in
+	InflationTo int `json:"inflationTo,omitempty"`
+}
+
+// OccupationResult holds the figures for one requested occupation
+type OccupationResult struct {
+	OccupationSelection
+	Status                    string     `json:"status"`
+	MatchingJobs              int        `json:"matchingJobs"`
+	Percentage                float64    `json:"percentage"`
+	PercentageRegion          float64    `json:"percentageRegion"`
+	EstimatedMatchingJobs     int        `json:"estimatedMatchingJobs"`
+	EstimatedPercentage       float64    `json:"estimatedPercentage"`
+	EstimatedPercentageRegion float64    `json:"estimatedPercentageRegion"`
+	SalaryInfo                SalaryInfo `json:"salaryInfo"`
 }
 
-// SalaryInfo provides detailed salary information
+// Result statuses reported by CalculationResult and OccupationResult
+const (
+	// statusMatched means rows matched and at least one published a wage
+	statusMatched = "matched"
+	// statusNoRows means no occupation matched the location and filters
+	statusNoRows = "noRows"
+	// statusWageSuppressed means rows matched but none published a wage, so
+	// minSalary could not be tested
+	statusWageSuppressed = "wageSuppressed"
+)
+
+// SalaryInfo provides detailed salary information. Method reports how the
+// percentiles of the matching rows were combined.
 type SalaryInfo struct {
-	MedianSalary int `json:"medianSalary"`
-	Pct10Salary  int `json:"pct10Salary"`
-	Pct25Salary  int `json:"pct25Salary"`
-	Pct75Salary  int `json:"pct75Salary"`
-	Pct90Salary  int `json:"pct90Salary"`
+	MedianSalary int    `json:"medianSalary"`
+	Pct10Salary  int    `json:"pct10Salary"`
+	Pct25Salary  int    `json:"pct25Salary"`
+	Pct75Salary  int    `json:"pct75Salary"`
+	Pct90Salary  int    `json:"pct90Salary"`
+	Method       string `json:"method"`
+	// AnnualFromHourly marks figures that include annual wages derived from
+	// hourly-only rows (hourly × 2080)
+	AnnualFromHourly bool `json:"annualFromHourly,omitempty"`
+	// Flags tell an exact figure from a lower bound (top-coded) or a value
+	// no matching row published (suppressed, reported as 0)
+	Flags *SalaryFlags `json:"flags,omitempty"`
+}
+
+// SalaryFlags holds the wageExact / wageTopCoded / wageSuppressed status of
+// each SalaryInfo percentile
+type SalaryFlags struct {
+	MedianSalary string `json:"medianSalary"`
+	Pct10Salary  string `json:"pct10Salary"`
+	Pct25Salary  string `json:"pct25Salary"`
+	Pct75Salary  string `json:"pct75Salary"`
+	Pct90Salary  string `json:"pct90Salary"`
+}
+
+// HourlyInfo provides the hourly wage percentiles, rounded to cents. Rows
+// publishing only annual wages do not contribute.
+type HourlyInfo struct {
+	MedianHourly float64 `json:"medianHourly"`
+	Pct10Hourly  float64 `json:"pct10Hourly"`
+	Pct25Hourly  float64 `json:"pct25Hourly"`
+	Pct75Hourly  float64 `json:"pct75Hourly"`
+	Pct90Hourly  float64 `json:"pct90Hourly"`
+}
+
+// TrendResult represents the /api/trend response
+type TrendResult struct {
+	Location   string `json:"location"`
+	Occupation string `json:"occupation"`
+	// Occupations lists the selections when several were combined
+	Occupations []OccupationSelection `json:"occupations,omitempty"`
+	Points      []TrendPoint          `json:"points"`
+	Count       int                   `json:"count"`
+	// InflationTo is the year whose dollars the wages are restated in
+	InflationTo int `json:"inflationTo,omitempty"`
+}
+
+// TrendPoint holds one release year of a trend, with changes relative to the
+// previous loaded year (nil for the first year)
+type TrendPoint struct {
+	Year       int          `json:"year"`
+	TotEmp     int          `json:"totEmp"`
+	SalaryInfo SalaryInfo   `json:"salaryInfo"`
+	Change     *TrendChange `json:"change"`
+}
+
+// TrendChange describes absolute and percent change from the previous year.
+// Percent fields are nil when the previous value is zero.
+type TrendChange struct {
+	FromYear        int      `json:"fromYear"`
+	TotEmp          int      `json:"totEmp"`
+	TotEmpPercent   *float64 `json:"totEmpPercent"`
+	MedianSalary    int      `json:"medianSalary"`
+	MedianSalaryPct *float64 `json:"medianSalaryPercent"`
 }
 
-// Handlers struct holds the database connection
+// Handlers struct holds the career data store
 type Handlers struct {
-	db *sql.DB
+	store         CareerDataStore
+	compareLimits CompareLimits
+	// zips resolves ZIP codes and cities to areas; nil when no crosswalk is loaded
+	zips *ZIPCrosswalk
+	// centroids locates areas for radiusMiles searches; nil when none are loaded
+	centroids *AreaCentroids
 }
 
 // NewHandlers creates a new Handlers instance
-func NewHandlers(db *sql.DB) *Handlers {
-	return &Handlers{db: db}
+func NewHandlers(store CareerDataStore) *Handlers {
+	return &Handlers{store: store, compareLimits: defaultCompareLimits}
 }
 
 // CalculateHandler handles the /api/calculate endpoint
 func (h *Handlers) CalculateHandler(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	filters := Filters{
-		Location:   r.URL.Query().Get("location"),
-		Occupation: r.URL.Query().Get("occupation"),
-		MinSalary:  parseMinSalary(r.URL.Query().Get("minSalary")),
-		Education:  r.URL.Query().Get("education"),
-		Experience: r.URL.Query().Get("experience"),
+	// Resolve the data year, defaulting to the latest loaded release
+	year, err := h.resolveYear(r.Context(), r.URL.Query().Get("year"))
+	if err != nil {
+		writeError(w, err, "Error resolving data year")
+		return
 	}
 
-	// Validate required fields
-	if filters.Location == "" {
-		http.Error(w, "Location is required", http.StatusBadRequest)
+	// Parse and validate query parameters, resolving the scope, a zip or a
+	// radius search to its areas
+	filters, radius, err := h.parseCalculateFilters(r, year)
+	if err != nil {
+		writeError(w, err, "Error resolving location")
 		return
 	}
 
 	// Calculate results based on filters
-	result, err := h.calculateJobOpportunities(filters)
+	result, err := h.calculateJobOpportunities(r.Context(), filters)
 	if err != nil {
-		log.Printf("Error calculating job opportunities: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		writeError(w, err, "Error calculating job opportunities")
 		return
 	}
+	result.Radius = radius
 
 	// Set response headers
 	w.Header().Set("Content-Type", "application/json")
@@ -86,31 +290,242 @@ func (h *Handlers) CalculateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// OccupationsHandler provides a list of unique occupation titles
-func (h *Handlers) OccupationsHandler(w http.ResponseWriter, r *http.Request) {
-	// Query to get unique occupation titles
-	query := "SELECT DISTINCT occ_title FROM career_data WHERE occ_title IS NOT NULL AND occ_title != '' AND occ_title <> 'All Occupations' ORDER BY occ_title" // exclude aggregate row
+// TrendHandler handles the /api/trend endpoint: per-year employment and wages
+// for the filtered occupation/location across loaded historical releases
+func (h *Handlers) TrendHandler(w http.ResponseWriter, r *http.Request) {
+	filters, err := parseFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	rows, err := h.db.Query(query)
+	rows, _, err := h.selectionRows(r.Context(), filters, h.store.HistoryRows)
 	if err != nil {
-		log.Printf("Error querying occupations: %v", err)
+		writeError(w, err, "Error calculating trend")
+		return
+	}
+	points := buildTrendPoints(aggregateByYear(rows, filters))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(TrendResult{
+		Location:    filters.Location,
+		Occupation:  filters.Occupation,
+		Occupations: filters.Occupations,
+		InflationTo: filters.InflationTo,
+		Points:      points,
+		Count:       len(points),
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// YearsHandler lists the OEWS release years available for /api/calculate
+func (h *Handlers) YearsHandler(w http.ResponseWriter, r *http.Request) {
+	years, err := h.store.ListYears(r.Context())
+	if err != nil {
+		log.Printf("Error querying years: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var latest int
+	if len(years) > 0 {
+		latest = years[0]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"years":  years,
+		"latest": latest,
+		"count":  len(years),
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// parseFilters reads the shared calculation filters from the query string.
+// Location is required.
+func parseFilters(r *http.Request) (Filters, error) {
+	filters, err := parseCriteria(r)
+	if err != nil {
+		return filters, err
+	}
+	if filters.Location == "" && filters.AreaCode == "" {
+		return filters, requestError("Location is required")
+	}
+	return filters, nil
+}
+
+// parseCriteria reads the calculation filters without requiring a location,
+// for endpoints that evaluate the criteria across many areas
+func parseCriteria(r *http.Request) (Filters, error) {
+	q := r.URL.Query()
+	filters := Filters{
+		Location:   q.Get("location"),
+		OccGroup:   strings.TrimSpace(q.Get("occGroup")),
+		AreaCode:   strings.TrimSpace(q.Get("areaCode")),
+		MinSalary:  parseMinSalary(q.Get("minSalary")),
+		Education:  q.Get("education"),
+		Experience: q.Get("experience"),
+	}
+	selections, err := parseOccupationSelections(q["occupation"], q["occCode"])
+	if err != nil {
+		return filters, err
+	}
+	if len(selections) == 1 {
+		filters.Occupation, filters.OccCode = selections[0].Occupation, selections[0].OccCode
+	} else {
+		filters.Occupations = selections
+	}
+	if filters.OccGroup != "" {
+		if _, _, ok := socGroupPrefix(filters.OccGroup); !ok {
+			return filters, requestError("occGroup must be a SOC code such as 15-0000")
+		}
+	}
+	match, err := parseMatchMode("match", q.Get("match"), matchContains)
+	if err != nil {
+		return filters, err
+	}
+	filters.OccupationMatch = match
+	if filters.LocationMatch, err = parseMatchMode("locationMatch", q.Get("locationMatch"), matchExact); err != nil {
+		return filters, err
+	}
+	method, err := parseSalaryMethod(q.Get("salaryMethod"))
+	if err != nil {
+		return filters, err
+	}
+	filters.SalaryMethod = method
+	if filters.Adjust = q.Get("adjust"); filters.Adjust != adjustNone && !containsString(adjustModes, filters.Adjust) {
+		return filters, requestError("adjust must be one of: " + strings.Join(adjustModes, ", "))
+	}
+	if raw := q.Get("minHourly"); raw != "" {
+		hourly, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(hourly) || hourly <= 0 || hourly > maxMinHourly {
+			return filters, requestError(fmt.Sprintf("minHourly must be a positive hourly wage of at most %d", maxMinHourly))
+		}
+		if filters.MinSalary > 0 {
+			return filters, requestError("minSalary and minHourly cannot be combined")
+		}
+		filters.MinHourly = hourly
+		filters.MinSalary = int(math.Round(hourly * hoursPerYear))
+	}
+	if raw := q.Get("inflationTo"); raw != "" {
+		year, err := strconv.Atoi(raw)
+		if err != nil || len(raw) != 4 {
+			return filters, requestError("inflationTo must be a four-digit year")
+		}
+		filters.InflationTo = year
+	}
+	return filters, nil
+}
+
+// parseOccupationSelections pairs the repeated occupation and occCode
+// parameters. When both are given they must be repeated the same number of
+// times and are matched up by position (a title with its code); otherwise
+// every value is a selection of its own.
+func parseOccupationSelections(titles, codes []string) ([]OccupationSelection, error) {
+	var selections []OccupationSelection
+	switch {
+	case len(titles) > 0 && len(codes) > 0:
+		if len(titles) != len(codes) {
+			return nil, requestError("occupation and occCode must be repeated the same number of times when both are given")
+		}
+		for i := range titles {
+			selections = append(selections, OccupationSelection{Occupation: titles[i], OccCode: strings.TrimSpace(codes[i])})
+		}
+	default:
+		for _, t := range titles {
+			selections = append(selections, OccupationSelection{Occupation: t})
+		}
+		for _, c := range codes {
+			selections = append(selections, OccupationSelection{OccCode: strings.TrimSpace(c)})
+		}
+	}
+	if len(selections) > maxOccupationSelections {
+		return nil, requestError(fmt.Sprintf("at most %d occupations may be requested at once", maxOccupationSelections))
+	}
+	return selections, nil
+}
+
+// parseMatchMode validates a text match mode parameter, returning def when
+// it is not set
+func parseMatchMode(param, raw, def string) (string, error) {
+	if raw == "" {
+		return def, nil
+	}
+	for _, m := range matchModes {
+		if raw == m {
+			return m, nil
+		}
+	}
+	return "", requestError(param + " must be one of: " + strings.Join(matchModes, ", "))
+}
+
+// parseSalaryMethod validates the salaryMethod parameter, defaulting to
+// employment-weighted percentiles
+func parseSalaryMethod(raw string) (string, error) {
+	if raw == "" {
+		return salaryMethodWeighted, nil
+	}
+	for _, m := range salaryMethods {
+		if raw == m {
+			return m, nil
+		}
+	}
+	return "", requestError("salaryMethod must be one of: " + strings.Join(salaryMethods, ", "))
+}
+
+// requestError marks a client input problem that should be reported as 400
+type requestError string
+
+func (e requestError) Error() string { return string(e) }
+
+// writeError reports a requestError anywhere in err's chain as 400 and logs
+// anything else, prefixed with action, as an internal server error
+func writeError(w http.ResponseWriter, err error, action string) {
+	var reqErr requestError
+	if errors.As(err, &reqErr) {
+		http.Error(w, reqErr.Error(), http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
+	log.Printf("%s: %v", action, err)
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
 
-	var occupations []string
-	for rows.Next() {
-		var occTitle string
-		if err := rows.Scan(&occTitle); err != nil {
-			log.Printf("Error scanning occupation: %v", err)
-			continue
+// resolveYear validates the year parameter against the loaded releases.
+// An empty value selects the latest year available.
+func (h *Handlers) resolveYear(ctx context.Context, raw string) (int, error) {
+	years, err := h.store.ListYears(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if raw == "" {
+		if len(years) == 0 {
+			return 0, fmt.Errorf("no data years loaded")
+		}
+		return years[0], nil
+	}
+	year, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, requestError("year must be a four-digit number")
+	}
+	for _, y := range years {
+		if y == year {
+			return year, nil
 		}
-		occupations = append(occupations, occTitle)
 	}
+	return 0, requestError(fmt.Sprintf("no data loaded for year %d", year))
+}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating occupations: %v", err)
+// OccupationsHandler provides a list of unique occupation code/title pairs
+func (h *Handlers) OccupationsHandler(w http.ResponseWriter, r *http.Request) {
+	occupations, err := h.store.ListOccupations(r.Context())
+	if err != nil {
+		log.Printf("Error querying occupations: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -129,36 +544,50 @@ func (h *Handlers) OccupationsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// LocationsHandler provides a list of unique area titles (locations)
-// Excludes generic U.S.-wide labels
-func (h *Handlers) LocationsHandler(w http.ResponseWriter, r *http.Request) {
-	query := `
-        SELECT DISTINCT area_title
-        FROM career_data
-        WHERE area_title IS NOT NULL
-          AND area_title <> ''
-          AND area_title NOT IN ('U.S.', 'United States', 'USA', 'US')
-        ORDER BY area_title`
-
-	rows, err := h.db.Query(query)
+// OccupationGroupsHandler returns the SOC hierarchy (major → minor → broad →
+// detailed) of the loaded occupations. The optional level parameter limits
+// the depth of the tree.
+func (h *Handlers) OccupationGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	level := r.URL.Query().Get("level")
+	if level == "" {
+		level = socDetailed
+	}
+	if !containsString(socLevels, level) {
+		http.Error(w, "level must be one of: "+strings.Join(socLevels, ", "), http.StatusBadRequest)
+		return
+	}
+
+	occupations, err := h.store.ListOccupations(r.Context())
 	if err != nil {
-		log.Printf("Error querying locations: %v", err)
+		log.Printf("Error querying occupations: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
+	titles, err := h.store.OccupationGroupTitles(r.Context())
+	if err != nil {
+		log.Printf("Error querying occupation groups: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	groups := buildOccupationGroupTree(occupations, titles, level)
 
-	var locations []string
-	for rows.Next() {
-		var area string
-		if err := rows.Scan(&area); err != nil {
-			log.Printf("Error scanning location: %v", err)
-			continue
-		}
-		locations = append(locations, area)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"groups": groups,
+		"count":  len(groups),
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating locations: %v", err)
+}
+
+// LocationsHandler provides a list of unique area titles (locations)
+// Excludes generic U.S.-wide labels
+func (h *Handlers) LocationsHandler(w http.ResponseWriter, r *http.Request) {
+	locations, err := h.store.ListAreas(r.Context())
+	if err != nil {
+		log.Printf("Error querying locations: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -176,38 +605,12 @@ func (h *Handlers) LocationsHandler(w http.ResponseWriter, r *http.Request) {
 
 // StatesHandler returns distinct state-level area titles
 func (h *Handlers) StatesHandler(w http.ResponseWriter, r *http.Request) {
-	query := `
-        SELECT DISTINCT area_title
-        FROM career_data
-        WHERE area_title IS NOT NULL
-          AND area_title <> ''
-          AND area_title NOT ILIKE '%,%'
-          AND area_title NOT ILIKE '%nonmetropolitan area%'
-          AND area_title NOT IN ('U.S.', 'United States', 'USA', 'US')
-        ORDER BY area_title`
-
-	rows, err := h.db.Query(query)
+	states, err := h.store.ListStates(r.Context())
 	if err != nil {
 		log.Printf("Error querying states: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-
-	var states []string
-	for rows.Next() {
-		var s string
-		if err := rows.Scan(&s); err != nil {
-			log.Printf("Error scanning state: %v", err)
-			continue
-		}
-		states = append(states, s)
-	}
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating states: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -220,47 +623,24 @@ func (h *Handlers) StatesHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// AreasByStateHandler returns all area titles relevant to a given state
+// AreasByStateHandler returns all area titles relevant to a given state,
+// named in full or by USPS abbreviation. Multi-state metros are listed under
+// every state they touch.
 func (h *Handlers) AreasByStateHandler(w http.ResponseWriter, r *http.Request) {
 	state := r.URL.Query().Get("state")
 	if state == "" {
 		http.Error(w, "Missing state parameter", http.StatusBadRequest)
 		return
 	}
-	abbr := stateNameToAbbr(state)
-	// Build patterns:
-	// 1) exact state name
-	// 2) MSAs that have ", {ABBR}" or ", {ABBR}-" after the comma
-	// 3) nonmetropolitan areas containing the state name
-	query := `
-        SELECT DISTINCT area_title
-        FROM career_data
-        WHERE area_title = $1
-           OR area_title ILIKE '%' || $2 || '%'
-           OR area_title ILIKE '%' || $3 || '%'
-        ORDER BY area_title`
-	commaPattern := ", " + abbr // matches ", GA" including cross-state like ", GA-SC"
-	nonMetroPattern := state + " nonmetropolitan area"
-
-	rows, err := h.db.Query(query, state, commaPattern, nonMetroPattern)
-	if err != nil {
-		log.Printf("Error querying areas by state: %v", err)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	state, _, ok := lookupState(state)
+	if !ok {
+		http.Error(w, "Unknown state", http.StatusBadRequest)
 		return
 	}
-	defer rows.Close()
 
-	var areas []string
-	for rows.Next() {
-		var a string
-		if err := rows.Scan(&a); err != nil {
-			log.Printf("Error scanning area: %v", err)
-			continue
-		}
-		areas = append(areas, a)
-	}
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating areas: %v", err)
+	areas, err := h.store.AreasForState(r.Context(), state)
+	if err != nil {
+		log.Printf("Error querying areas by state: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -276,26 +656,32 @@ func (h *Handlers) AreasByStateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// stateNameToAbbr maps state full names to USPS abbreviations
-func stateNameToAbbr(state string) string {
-	m := map[string]string{
-		"Alabama": "AL", "Alaska": "AK", "Arizona": "AZ", "Arkansas": "AR", "California": "CA",
-		"Colorado": "CO", "Connecticut": "CT", "Delaware": "DE", "District of Columbia": "DC",
-		"Florida": "FL", "Georgia": "GA", "Hawaii": "HI", "Idaho": "ID", "Illinois": "IL",
-		"Indiana": "IN", "Iowa": "IA", "Kansas": "KS", "Kentucky": "KY", "Louisiana": "LA",
-		"Maine": "ME", "Maryland": "MD", "Massachusetts": "MA", "Michigan": "MI", "Minnesota": "MN",
-		"Mississippi": "MS", "Missouri": "MO", "Montana": "MT", "Nebraska": "NE", "Nevada": "NV",
-		"New Hampshire": "NH", "New Jersey": "NJ", "New Mexico": "NM", "New York": "NY",
-		"North Carolina": "NC", "North Dakota": "ND", "Ohio": "OH", "Oklahoma": "OK", "Oregon": "OR",
-		"Pennsylvania": "PA", "Rhode Island": "RI", "South Carolina": "SC", "South Dakota": "SD",
-		"Tennessee": "TN", "Texas": "TX", "Utah": "UT", "Vermont": "VT", "Virginia": "VA",
-		"Washington": "WA", "West Virginia": "WV", "Wisconsin": "WI", "Wyoming": "WY",
-		"Puerto Rico": "PR", "Guam": "GU", "Virgin Islands": "VI",
-	}
-	if v, ok := m[state]; ok {
-		return v
+// stateAbbrs maps state and territory names to USPS abbreviations
+var stateAbbrs = map[string]string{
+	"Alabama": "AL", "Alaska": "AK", "Arizona": "AZ", "Arkansas": "AR", "California": "CA",
+	"Colorado": "CO", "Connecticut": "CT", "Delaware": "DE", "District of Columbia": "DC",
+	"Florida": "FL", "Georgia": "GA", "Hawaii": "HI", "Idaho": "ID", "Illinois": "IL",
+	"Indiana": "IN", "Iowa": "IA", "Kansas": "KS", "Kentucky": "KY", "Louisiana": "LA",
+	"Maine": "ME", "Maryland": "MD", "Massachusetts": "MA", "Michigan": "MI", "Minnesota": "MN",
+	"Mississippi": "MS", "Missouri": "MO", "Montana": "MT", "Nebraska": "NE", "Nevada": "NV",
+	"New Hampshire": "NH", "New Jersey": "NJ", "New Mexico": "NM", "New York": "NY",
+	"North Carolina": "NC", "North Dakota": "ND", "Ohio": "OH", "Oklahoma": "OK", "Oregon": "OR",
+	"Pennsylvania": "PA", "Rhode Island": "RI", "South Carolina": "SC", "South Dakota": "SD",
+	"Tennessee": "TN", "Texas": "TX", "Utah": "UT", "Vermont": "VT", "Virginia": "VA",
+	"Washington": "WA", "West Virginia": "WV", "Wisconsin": "WI", "Wyoming": "WY",
+	"Puerto Rico": "PR", "Guam": "GU", "Virgin Islands": "VI",
+}
+
+// lookupState resolves a state or territory given by name or USPS
+// abbreviation (case-insensitive) to its canonical name and abbreviation
+func lookupState(state string) (name, abbr string, ok bool) {
+	state = strings.TrimSpace(state)
+	for n, a := range stateAbbrs {
+		if strings.EqualFold(n, state) || strings.EqualFold(a, state) {
+			return n, a, true
+		}
 	}
-	return state // fallback
+	return "", "", false
 }
 
 // HealthHandler provides a simple health check endpoint
@@ -306,37 +692,39 @@ func (h *Handlers) HealthHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // calculateJobOpportunities performs the main calculation logic
-func (h *Handlers) calculateJobOpportunities(filters Filters) (*CalculationResult, error) {
-	// Build the SQL query based on filters
-	query, args := buildQuery(filters)
-
-	// Execute the query to get matching jobs count and salary info
-	var matchingJobs sql.NullFloat64
-	var medianSalary, pct10Salary, pct25Salary, pct75Salary, pct90Salary sql.NullFloat64
-	var totalEmp sql.NullFloat64
-
-	err := h.db.QueryRow(query, args...).Scan(
-		&matchingJobs, &medianSalary, &pct10Salary, &pct25Salary, &pct75Salary, &pct90Salary, &totalEmp,
-	)
+func (h *Handlers) calculateJobOpportunities(ctx context.Context, filters Filters) (*CalculationResult, error) {
+	// Get matching rows (combined across requested occupations) and aggregate jobs count and salary info
+	rows, perSelection, err := h.selectionRows(ctx, filters, h.store.MatchingRows)
 	if err != nil {
-		return nil, fmt.Errorf("error querying matching jobs: %v", err)
+		return nil, err
 	}
+	agg := aggregateRows(rows, filters)
+	matchingJobs := agg.MatchingJobs
+	medianSalary := agg.Median
 
-	// Get total jobs count across all locations using the NATIONAL aggregated row for occ_code '00-0000'.
-	// Some datasets include many '00-0000' rows (one per area). We want the SINGLE national total, which should have the
-	// largest tot_emp for that occ_code. Ordering by tot_emp DESC ensures we pick the correct national aggregate even if
-	// area_title filters (e.g., 'U.S.') vary or were transformed during preprocessing.
-	var totalJobs int
-	err = h.db.QueryRow("SELECT tot_emp FROM career_data WHERE occ_code = '00-0000' ORDER BY tot_emp DESC LIMIT 1").Scan(&totalJobs)
+	// Get total jobs count across all locations (national denominator)
+	totalJobs, err := h.store.NationalTotal(ctx, filters.Year)
 	if err != nil {
-		return nil, fmt.Errorf("error querying total jobs: %v", err)
+		return nil, err
 	}
 
-	// Get total jobs count for the selected region/location only (denominator for regional view)
-	var totalJobsRegion int
-	err = h.db.QueryRow("SELECT SUM(tot_emp) FROM career_data WHERE area_title = $1", filters.Location).Scan(&totalJobsRegion)
+	// Get total jobs count for the selected region/location only (denominator for regional view),
+	// scoped by the same location filters as the matching rows
+	totalJobsRegion, err := h.store.RegionalTotal(ctx, filters)
 	if err != nil {
-		return nil, fmt.Errorf("error querying regional total jobs: %v", err)
+		return nil, err
+	}
+
+	// Describe the matched area from the area metadata, reporting its title
+	// when the request selected it by code
+	areas, err := h.store.Areas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	area := matchedArea(areas, filters, rows)
+	location := filters.Location
+	if location == "" {
+		location = area.Title
 	}
 
 	// Calculate percentage
@@ -351,6 +739,16 @@ func (h *Handlers) calculateJobOpportunities(filters Filters) (*CalculationResul
 		percentageRegion = (matchingJobs.Float64 / float64(totalJobsRegion)) * 100
 	}
 
+	// Estimated percentages from the interpolated wage distribution
+	estimatedJobs := agg.EstimatedMatchingJobs
+	var estimatedPercentage, estimatedPercentageRegion float64
+	if totalJobs > 0 && estimatedJobs.Valid {
+		estimatedPercentage = (estimatedJobs.Float64 / float64(totalJobs)) * 100
+	}
+	if totalJobsRegion > 0 && estimatedJobs.Valid {
+		estimatedPercentageRegion = (estimatedJobs.Float64 / float64(totalJobsRegion)) * 100
+	}
+
 	// Check if minimum salary requirement is met
 	minSalaryMet := false
 	if medianSalary.Valid && filters.MinSalary > 0 {
@@ -358,54 +756,352 @@ func (h *Handlers) calculateJobOpportunities(filters Filters) (*CalculationResul
 	}
 
 	// Build salary info
-	salaryInfo := SalaryInfo{
-		MedianSalary: int(medianSalary.Float64),
-		Pct10Salary:  int(pct10Salary.Float64),
-		Pct25Salary:  int(pct25Salary.Float64),
-		Pct75Salary:  int(pct75Salary.Float64),
-		Pct90Salary:  int(pct90Salary.Float64),
+	salaryInfo := salaryInfoFrom(agg)
+
+	// Per-occupation figures when several occupations were requested
+	var breakdown []OccupationResult
+	for i, selection := range filters.Occupations {
+		sel := aggregateRows(perSelection[i], filters)
+		breakdown = append(breakdown, OccupationResult{
+			OccupationSelection:       selection,
+			Status:                    resultStatus(perSelection[i]),
+			MatchingJobs:              int(sel.MatchingJobs.Float64),
+			Percentage:                percentOf(sel.MatchingJobs, totalJobs),
+			PercentageRegion:          percentOf(sel.MatchingJobs, totalJobsRegion),
+			EstimatedMatchingJobs:     int(math.Round(sel.EstimatedMatchingJobs.Float64)),
+			EstimatedPercentage:       percentOf(sel.EstimatedMatchingJobs, totalJobs),
+			EstimatedPercentageRegion: percentOf(sel.EstimatedMatchingJobs, totalJobsRegion),
+			SalaryInfo:                salaryInfoFrom(sel),
+		})
 	}
 
 	return &CalculationResult{
-		Percentage:       percentage,
-		PercentageRegion: percentageRegion,
-		MatchingJobs:     int(matchingJobs.Float64),
-		TotalJobs:        totalJobs,
-		TotalJobsRegion:  totalJobsRegion,
-		Location:         filters.Location,
-		MinSalaryMet:     minSalaryMet,
-		SalaryInfo:       salaryInfo,
+		Percentage:                percentage,
+		PercentageRegion:          percentageRegion,
+		MatchingJobs:              int(matchingJobs.Float64),
+		TotalJobs:                 totalJobs,
+		TotalJobsRegion:           totalJobsRegion,
+		Location:                  location,
+		Year:                      filters.Year,
+		MinSalaryMet:              minSalaryMet,
+		SalaryInfo:                salaryInfo,
+		HourlyInfo:                hourlyInfoFrom(agg),
+		Status:                    resultStatus(rows),
+		Scope:                     filters.Scope,
+		AreaCode:                  area.Code,
+		AreaType:                  area.Type,
+		EstimatedMatchingJobs:     int(math.Round(estimatedJobs.Float64)),
+		EstimatedPercentage:       estimatedPercentage,
+		EstimatedPercentageRegion: estimatedPercentageRegion,
+		Breakdown:                 breakdown,
+		Adjust:                    filters.Adjust,
+		PriceParities:             appliedPriceParities(rows),
+		InflationTo:               filters.InflationTo,
 	}, nil
 }
 
-// buildQuery constructs the SQL query and arguments based on filters
-func buildQuery(filters Filters) (string, []interface{}) {
-	baseQuery := `
-		SELECT 
-			SUM(tot_emp) as matching_jobs,
-			AVG(a_median) as median_salary,
-			AVG(a_pct10) as pct10_salary,
-			AVG(a_pct25) as pct25_salary,
-			AVG(a_pct75) as pct75_salary,
-			AVG(a_pct90) as pct90_salary,
-			SUM(tot_emp) as total_emp
-		FROM career_data 
-		WHERE 1=1`
+// selectionRows fetches rows for each requested occupation and returns their
+// union (a row matched by several selections is counted once) along with the
+// rows of each selection. Wages are adjusted first when the filters ask for
+// it (adjust=rpp, inflationTo).
+func (h *Handlers) selectionRows(ctx context.Context, filters Filters,
+	fetch func(context.Context, Filters) ([]careerRow, error)) ([]careerRow, [][]careerRow, error) {
+	if filters.Adjust == adjustRPP {
+		parities, err := h.store.PriceParities(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		nominal := fetch
+		fetch = func(ctx context.Context, f Filters) ([]careerRow, error) {
+			rows, err := nominal(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+			return applyPriceParities(rows, parities), nil
+		}
+	}
+	if filters.InflationTo != 0 {
+		index, err := h.store.CPIIndex(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		current := fetch
+		fetch = func(ctx context.Context, f Filters) ([]careerRow, error) {
+			rows, err := current(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+			return applyInflation(rows, index, filters.InflationTo)
+		}
+	}
+	expanded := filters.selectionFilters()
+	if len(expanded) == 1 {
+		rows, err := fetch(ctx, expanded[0])
+		return rows, [][]careerRow{rows}, err
+	}
+
+	seen := make(map[careerKey]bool)
+	var union []careerRow
+	perSelection := make([][]careerRow, 0, len(expanded))
+	for _, f := range expanded {
+		rows, err := fetch(ctx, f)
+		if err != nil {
+			return nil, nil, err
+		}
+		perSelection = append(perSelection, rows)
+		for _, r := range rows {
+			if !seen[r.key()] {
+				seen[r.key()] = true
+				union = append(union, r)
+			}
+		}
+	}
+	return union, perSelection, nil
+}
+
+// percentOf returns part as a percentage of total, or 0 when either is missing
+func percentOf(part sql.NullFloat64, total int) float64 {
+	if total <= 0 || !part.Valid {
+		return 0
+	}
+	return part.Float64 / float64(total) * 100
+}
+
+// buildTrendPoints converts per-year aggregates (oldest first) into trend
+// points with year-over-year changes
+func buildTrendPoints(years []YearAggregate) []TrendPoint {
+	points := make([]TrendPoint, 0, len(years))
+	for i, y := range years {
+		point := TrendPoint{
+			Year:       y.Year,
+			TotEmp:     int(y.MatchingJobs.Float64),
+			SalaryInfo: salaryInfoFrom(y.JobAggregate),
+		}
+		if i > 0 {
+			prev := points[i-1]
+			point.Change = &TrendChange{
+				FromYear:        prev.Year,
+				TotEmp:          point.TotEmp - prev.TotEmp,
+				TotEmpPercent:   percentChange(prev.TotEmp, point.TotEmp),
+				MedianSalary:    point.SalaryInfo.MedianSalary - prev.SalaryInfo.MedianSalary,
+				MedianSalaryPct: percentChange(prev.SalaryInfo.MedianSalary, point.SalaryInfo.MedianSalary),
+			}
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// matchedArea returns the metadata of the single area a calculation covers:
+// the area of every matched row, or without rows the area selected by code
+// or exact title. The zero Area is returned when several areas matched or
+// an area list names several.
+func matchedArea(areas map[string]Area, filters Filters, rows []careerRow) Area {
+	if len(filters.Areas) > 1 {
+		return Area{}
+	}
+	if len(filters.Areas) == 1 {
+		if a, ok := areas[filters.Areas[0]]; ok {
+			return a
+		}
+		return Area{Title: filters.Areas[0], Type: classifyAreaTitle(filters.Areas[0])}
+	}
+	var title string
+	for _, r := range rows {
+		if title != "" && r.AreaTitle != title {
+			return Area{}
+		}
+		title = r.AreaTitle
+	}
+	if title == "" {
+		for _, a := range areas {
+			if (filters.AreaCode != "" && a.Code == filters.AreaCode) ||
+				(filters.AreaCode == "" && filters.Location != "" && strings.EqualFold(a.Title, filters.Location)) {
+				return a
+			}
+		}
+		return Area{}
+	}
+	if a, ok := areas[title]; ok {
+		return a
+	}
+	return Area{Title: title, Type: classifyAreaTitle(title)}
+}
+
+// resultStatus classifies the rows matched by a calculation, before the
+// minSalary test, as statusNoRows, statusWageSuppressed or statusMatched
+func resultStatus(rows []careerRow) string {
+	if len(rows) == 0 {
+		return statusNoRows
+	}
+	for _, r := range rows {
+		r, _ := deriveAnnualWages(r)
+		for _, p := range rowPercentiles(r) {
+			if p.Valid {
+				return statusMatched
+			}
+		}
+	}
+	return statusWageSuppressed
+}
+
+// salaryInfoFrom converts aggregated wages into the response shape
+func salaryInfoFrom(agg JobAggregate) SalaryInfo {
+	var flags *SalaryFlags
+	if s := agg.WageStatus; s[0] != "" {
+		flags = &SalaryFlags{Pct10Salary: s[0], Pct25Salary: s[1], MedianSalary: s[2], Pct75Salary: s[3], Pct90Salary: s[4]}
+	}
+	return SalaryInfo{
+		MedianSalary: int(agg.Median.Float64),
+		Pct10Salary:  int(agg.Pct10.Float64),
+		Pct25Salary:  int(agg.Pct25.Float64),
+		Pct75Salary:  int(agg.Pct75.Float64),
+		Pct90Salary:  int(agg.Pct90.Float64),
+		Method:       agg.SalaryMethod,
+
+		AnnualFromHourly: agg.AnnualFromHourly,
+		Flags:            flags,
+	}
+}
+
+// hourlyInfoFrom builds the hourly wage percentiles of an aggregate
+func hourlyInfoFrom(agg JobAggregate) HourlyInfo {
+	cents := func(v sql.NullFloat64) float64 { return math.Round(v.Float64*100) / 100 }
+	return HourlyInfo{
+		MedianHourly: cents(agg.HourlyMedian),
+		Pct10Hourly:  cents(agg.HourlyPct10),
+		Pct25Hourly:  cents(agg.HourlyPct25),
+		Pct75Hourly:  cents(agg.HourlyPct75),
+		Pct90Hourly:  cents(agg.HourlyPct90),
+	}
+}
+
+// percentChange returns (to-from)/from*100, or nil when from is zero
+func percentChange(from, to int) *float64 {
+	if from == 0 {
+		return nil
+	}
+	pct := float64(to-from) / float64(from) * 100
+	return &pct
+}
+
+// buildQuery constructs the SQL query and arguments selecting the career_data
+// rows that match the filters, rendering operators and placeholders for the
+// given dialect. The salary threshold is not part of the query: aggregateRows
+// applies it so rows below the threshold can still feed the estimated count.
+func buildQuery(d dialect, filters Filters) (string, []interface{}) {
+	return buildRowsQuery(d, careerDataTable, filters)
+}
 
+// buildHistoryQuery selects the career_data_history rows matching the filters
+// across every loaded year, for aggregation per year
+func buildHistoryQuery(d dialect, filters Filters) (string, []interface{}) {
+	filters.Year = 0
+	return buildRowsQuery(d, careerHistoryTable, filters)
+}
+
+// buildRowsQuery selects the careerRow columns of table for the filters,
+// excluding the salary threshold
+func buildRowsQuery(d dialect, table string, filters Filters) (string, []interface{}) {
+	filters.MinSalary = 0
+	where, args := buildWhereClause(d, filters)
+	return `
+		SELECT
+			data_year, area_code, area_title, occ_code, occ_title, education, experience,
+			tot_emp, a_median, a_pct10, a_pct25, a_pct75, a_pct90,
+			h_median, h_pct10, h_pct25, h_pct75, h_pct90, a_top_coded, h_top_coded
+		FROM ` + table + `
+		WHERE 1=1` + where + `
+		ORDER BY data_year`, args
+}
+
+// Text match modes for title filters
+const (
+	matchContains = "contains"
+	matchExact    = "exact"
+	matchPrefix   = "prefix"
+)
+
+// matchModes lists the accepted match values, default first
+var matchModes = []string{matchContains, matchExact, matchPrefix}
+
+// textMatchClause renders a case-insensitive comparison of column with the
+// value bound to placeholder, returning the predicate and the value to bind
+func textMatchClause(d dialect, column, mode, value, placeholder string) (string, interface{}) {
+	switch mode {
+	case matchExact:
+		return fmt.Sprintf("LOWER(%s) = LOWER(%s)", column, placeholder), value
+	case matchPrefix:
+		return fmt.Sprintf("%s %s %s", column, d.ilike, placeholder), value + "%"
+	default:
+		return fmt.Sprintf("%s %s %s", column, d.ilike, placeholder), "%" + value + "%"
+	}
+}
+
+// buildWhereClause renders the filter conditions shared by every career data
+// query as a sequence of " AND ..." predicates plus their arguments
+func buildWhereClause(d dialect, filters Filters) (string, []interface{}) {
+	var baseQuery string
 	var args []interface{}
 	argCount := 1
 
+	// Exclude each area's "All Occupations" row, which duplicates its detailed rows
+	baseQuery += " AND occ_code <> '00-0000'"
+
 	// Add location filter
 	if filters.Location != "" {
-		baseQuery += fmt.Sprintf(" AND area_title ILIKE $%d", argCount)
-		args = append(args, "%"+filters.Location+"%")
+		clause, arg := textMatchClause(d, "area_title", filters.locationMatchMode(), filters.Location, d.placeholder(argCount))
+		baseQuery += " AND " + clause
+		args = append(args, arg)
 		argCount++
 	}
 
+	// Add area code filter
+	if filters.AreaCode != "" {
+		baseQuery += fmt.Sprintf(" AND area_code = %s", d.placeholder(argCount))
+		args = append(args, filters.AreaCode)
+		argCount++
+	}
+
+	// Add area list filter
+	if len(filters.Areas) > 0 {
+		placeholders := make([]string, len(filters.Areas))
+		for i, title := range filters.Areas {
+			placeholders[i] = d.placeholder(argCount)
+			args = append(args, title)
+			argCount++
+		}
+		baseQuery += " AND area_title IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
 	// Add occupation filter
 	if filters.Occupation != "" {
-		baseQuery += fmt.Sprintf(" AND occ_title ILIKE $%d", argCount)
-		args = append(args, "%"+filters.Occupation+"%")
+		clause, arg := textMatchClause(d, "occ_title", filters.OccupationMatch, filters.Occupation, d.placeholder(argCount))
+		baseQuery += " AND " + clause
+		args = append(args, arg)
+		argCount++
+	}
+
+	// Add occupation code filter
+	if filters.OccCode != "" {
+		baseQuery += fmt.Sprintf(" AND occ_code = %s", d.placeholder(argCount))
+		args = append(args, filters.OccCode)
+		argCount++
+	}
+
+	// Add SOC group filter: every occupation code under the group's prefix
+	if filters.OccGroup != "" {
+		if prefix, _, ok := socGroupPrefix(filters.OccGroup); ok {
+			baseQuery += fmt.Sprintf(" AND occ_code LIKE %s", d.placeholder(argCount))
+			args = append(args, prefix+"%")
+			argCount++
+		}
+	}
+
+	// Add data year filter
+	if filters.Year > 0 {
+		baseQuery += fmt.Sprintf(" AND data_year = %s", d.placeholder(argCount))
+		args = append(args, filters.Year)
 		argCount++
 	}
 
@@ -414,13 +1110,13 @@ func buildQuery(filters Filters) (string, []interface{}) {
 		allowedEdu := getAllowedEducationValues(filters.Education)
 		if len(allowedEdu) == 1 && allowedEdu[0] == "__EXACT__POSTSECONDARY_NONDEGREE__" {
 			// Exact match for non-ladder value
-			baseQuery += fmt.Sprintf(" AND education = $%d", argCount)
+			baseQuery += fmt.Sprintf(" AND education = %s", d.placeholder(argCount))
 			args = append(args, "Postsecondary nondegree award")
 			argCount++
 		} else if len(allowedEdu) > 0 {
 			placeholders := make([]string, 0, len(allowedEdu))
 			for _, v := range allowedEdu {
-				placeholders = append(placeholders, fmt.Sprintf("$%d", argCount))
+				placeholders = append(placeholders, d.placeholder(argCount))
 				args = append(args, v)
 				argCount++
 			}
@@ -443,7 +1139,7 @@ func buildQuery(filters Filters) (string, []interface{}) {
 
 			placeholders := make([]string, 0, len(allowedExp))
 			for _, v := range allowedExp {
-				placeholders = append(placeholders, fmt.Sprintf("$%d", argCount))
+				placeholders = append(placeholders, d.placeholder(argCount))
 				args = append(args, v)
 				argCount++
 			}
@@ -459,7 +1155,8 @@ func buildQuery(filters Filters) (string, []interface{}) {
 
 	// Add salary filter - inclusive across distribution percentiles
 	if filters.MinSalary > 0 {
-		baseQuery += fmt.Sprintf(" AND (a_median >= $%d OR a_pct75 >= $%d OR a_pct90 >= $%d)", argCount, argCount, argCount)
+		p := d.placeholder(argCount)
+		baseQuery += fmt.Sprintf(" AND (a_median >= %s OR a_pct75 >= %s OR a_pct90 >= %s)", p, p, p)
 		args = append(args, filters.MinSalary)
 		argCount++
 	}
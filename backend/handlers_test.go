@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeStore is a CareerDataStore stub for exercising handlers without a database
+type fakeStore struct {
+	occupations []Occupation
+	rows        []careerRow
+	national    int
+	regional    map[string]int
+	years       []int
+	history     []careerRow
+	groups      map[string]string
+	parities    map[string]float64
+	cpi         map[int]float64
+	areas       map[string]Area
+	lastFilters Filters
+}
+
+func (f *fakeStore) ListOccupations(ctx context.Context) ([]Occupation, error) {
+	return f.occupations, nil
+}
+
+func (f *fakeStore) ListAreas(ctx context.Context) ([]string, error) { return nil, nil }
+
+func (f *fakeStore) ListStates(ctx context.Context) ([]string, error) { return nil, nil }
+
+func (f *fakeStore) AreasForState(ctx context.Context, state string) ([]string, error) {
+	return nil, nil
+}
+
+func (f *fakeStore) MatchingRows(ctx context.Context, filters Filters) ([]careerRow, error) {
+	f.lastFilters = filters
+	return f.rows, nil
+}
+
+func (f *fakeStore) NationalTotal(ctx context.Context, year int) (int, error) { return f.national, nil }
+
+func (f *fakeStore) NationalArea(ctx context.Context, year int) (string, error) { return "", nil }
+
+func (f *fakeStore) RegionalTotal(ctx context.Context, filters Filters) (int, error) {
+	return f.regional[filters.Location], nil
+}
+
+func (f *fakeStore) AreaTotals(ctx context.Context, year int) (map[string]int, error) {
+	return f.regional, nil
+}
+
+func (f *fakeStore) OccupationGroupTitles(ctx context.Context) (map[string]string, error) {
+	return f.groups, nil
+}
+
+func (f *fakeStore) PriceParities(ctx context.Context) (map[string]float64, error) {
+	return f.parities, nil
+}
+
+func (f *fakeStore) CPIIndex(ctx context.Context) (map[int]float64, error) { return f.cpi, nil }
+
+func (f *fakeStore) Areas(ctx context.Context) (map[string]Area, error) { return f.areas, nil }
+
+func (f *fakeStore) ListYears(ctx context.Context) ([]int, error) { return f.years, nil }
+
+func (f *fakeStore) HistoryRows(ctx context.Context, filters Filters) ([]careerRow, error) {
+	f.lastFilters = filters
+	return f.history, nil
+}
+
+func TestCalculateHandlerUsesStore(t *testing.T) {
+	store := &fakeStore{
+		rows: []careerRow{{
+			AreaTitle: "Testville, MI",
+			OccTitle:  "Nurse",
+			TotEmp:    sql.NullFloat64{Float64: 500, Valid: true},
+			Median:    sql.NullFloat64{Float64: 90000, Valid: true},
+		}},
+		national: 100000,
+		regional: map[string]int{"Testville, MI": 10000},
+		years:    []int{2023, 2022},
+	}
+	h := NewHandlers(store)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/api/calculate?location=Testville,+MI&occupation=Nurse&minSalary=80000", nil)
+	h.CalculateHandler(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+
+	var result CalculationResult
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if result.MatchingJobs != 500 || result.TotalJobs != 100000 || result.TotalJobsRegion != 10000 {
+		t.Errorf("unexpected counts: %+v", result)
+	}
+	if result.PercentageRegion != 5 {
+		t.Errorf("expected regional percentage 5, got %v", result.PercentageRegion)
+	}
+	if !result.MinSalaryMet {
+		t.Errorf("expected minSalaryMet to be true")
+	}
+	if store.lastFilters.Occupation != "Nurse" || store.lastFilters.MinSalary != 80000 {
+		t.Errorf("filters not passed to store: %+v", store.lastFilters)
+	}
+	if result.Year != 2023 || store.lastFilters.Year != 2023 {
+		t.Errorf("expected the latest year to be the default, got %d", result.Year)
+	}
+	if result.SalaryInfo.Method != salaryMethodWeighted {
+		t.Errorf("expected salaryInfo.method %q, got %q", salaryMethodWeighted, result.SalaryInfo.Method)
+	}
+
+	rr = httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Testville,+MI&salaryMethod=median", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown salaryMethod, got %d", rr.Code)
+	}
+}
+
+func TestCalculateHandlerYearParameter(t *testing.T) {
+	store := &fakeStore{years: []int{2023, 2022}}
+	h := NewHandlers(store)
+
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Michigan&year=2022", nil))
+	if rr.Code != http.StatusOK || store.lastFilters.Year != 2022 {
+		t.Fatalf("expected year 2022 to be used, got code %d filters %+v", rr.Code, store.lastFilters)
+	}
+
+	for _, year := range []string{"2019", "latest"} {
+		rr = httptest.NewRecorder()
+		h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Michigan&year="+year, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("year=%s: expected 400, got %d", year, rr.Code)
+		}
+	}
+}
+
+func TestCalculateHandlerRequiresLocation(t *testing.T) {
+	h := NewHandlers(&fakeStore{years: []int{2023}})
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?occupation=Nurse", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rr.Code)
+	}
+}
+
+func TestTrendHandlerReportsYearOverYearChange(t *testing.T) {
+	valid := func(v float64) sql.NullFloat64 { return sql.NullFloat64{Float64: v, Valid: true} }
+	store := &fakeStore{history: []careerRow{
+		{Year: 2023, TotEmp: valid(1100), Median: valid(84000)},
+		{Year: 2021, TotEmp: valid(0), Median: valid(80000)},
+		{Year: 2022, TotEmp: valid(1000), Median: valid(80000)},
+	}}
+	h := NewHandlers(store)
+
+	rr := httptest.NewRecorder()
+	h.TrendHandler(rr, httptest.NewRequest("GET", "/api/trend?location=Michigan&occupation=Nurse", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var result TrendResult
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 3 || result.Points[0].Change != nil {
+		t.Fatalf("unexpected points: %+v", result.Points)
+	}
+	if c := result.Points[1].Change; c.TotEmp != 1000 || c.TotEmpPercent != nil {
+		t.Errorf("expected no percent change from zero, got %+v", c)
+	}
+	c := result.Points[2].Change
+	if c.FromYear != 2022 || c.TotEmp != 100 || *c.TotEmpPercent != 10 || c.MedianSalary != 4000 || *c.MedianSalaryPct != 5 {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	if store.lastFilters.Occupation != "Nurse" {
+		t.Errorf("filters not passed to store: %+v", store.lastFilters)
+	}
+	if result.Points[2].SalaryInfo.Method != salaryMethodWeighted {
+		t.Errorf("expected the weighted method by default, got %q", result.Points[2].SalaryInfo.Method)
+	}
+
+	rr = httptest.NewRecorder()
+	h.TrendHandler(rr, httptest.NewRequest("GET", "/api/trend?occupation=Nurse", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without location, got %d", rr.Code)
+	}
+}
+
+func TestCalculateHandlerOccupationMatching(t *testing.T) {
+	store := &fakeStore{years: []int{2023}}
+	h := NewHandlers(store)
+
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Michigan&occupation=Registered+Nurses&match=exact&occCode=29-1141", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	if f := store.lastFilters; f.OccupationMatch != matchExact || f.OccCode != "29-1141" {
+		t.Errorf("match options not passed to store: %+v", f)
+	}
+
+	rr = httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Michigan&occupation=Nurse", nil))
+	if store.lastFilters.OccupationMatch != matchContains {
+		t.Errorf("expected contains by default, got %q", store.lastFilters.OccupationMatch)
+	}
+
+	rr = httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Michigan&occupation=Nurse&match=fuzzy", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown match mode, got %d", rr.Code)
+	}
+}
+
+func TestOccupationsHandlerReturnsCodeTitlePairs(t *testing.T) {
+	h := NewHandlers(&fakeStore{occupations: []Occupation{{Code: "29-1141", Title: "Registered Nurses"}}})
+	rr := httptest.NewRecorder()
+	h.OccupationsHandler(rr, httptest.NewRequest("GET", "/api/occupations", nil))
+
+	var body struct {
+		Occupations []Occupation `json:"occupations"`
+		Count       int          `json:"count"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 1 || body.Occupations[0] != (Occupation{Code: "29-1141", Title: "Registered Nurses"}) {
+		t.Errorf("unexpected occupations: %+v", body)
+	}
+}
+
+// "Washington" must not pull the Washington-Arlington-Alexandria metro into
+// the numerator while the denominator only covers the state
+func TestCalculateScopesLocationConsistently(t *testing.T) {
+	csv := `AREA,AREA_TITLE,OCC_CODE,OCC_TITLE,TOT_EMP,A_MEDIAN
+53,Washington,00-0000,All Occupations,3500000,60000
+53,Washington,29-1141,Registered Nurses,60000,100000
+53,Washington,15-1252,Software Developers,140000,150000
+47900,"Washington-Arlington-Alexandria, DC-VA-MD-WV",00-0000,All Occupations,3100000,70000
+47900,"Washington-Arlington-Alexandria, DC-VA-MD-WV",29-1141,Registered Nurses,55000,95000
+`
+	rows, err := readCareerCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandlers(NewMemoryStore(rows))
+
+	result := calculate(t, h, "location=Washington&occupation=Registered+Nurses")
+	if result.MatchingJobs != 60000 || result.TotalJobsRegion != 200000 || result.PercentageRegion != 30 {
+		t.Errorf("expected only the state in numerator and denominator, got %+v", result)
+	}
+
+	// Without an occupation every detailed row is counted once: 100% of the area
+	if result = calculate(t, h, "location=Washington"); result.MatchingJobs != 200000 || result.PercentageRegion != 100 {
+		t.Errorf("expected the All Occupations row to be excluded, got %+v", result)
+	}
+
+	// The legacy substring mode widens numerator and denominator together
+	result = calculate(t, h, "location=Washington&locationMatch=contains&occupation=Registered+Nurses")
+	if result.MatchingJobs != 115000 || result.TotalJobsRegion != 255000 {
+		t.Errorf("expected both areas on both sides, got %+v", result)
+	}
+
+	result = calculate(t, h, "areaCode=47900&occupation=Registered+Nurses")
+	if result.MatchingJobs != 55000 || result.TotalJobsRegion != 55000 || result.Location != "Washington-Arlington-Alexandria, DC-VA-MD-WV" {
+		t.Errorf("expected the metro selected by area code, got %+v", result)
+	}
+}
+
+func TestOccupationGroupsHandlerBuildsTree(t *testing.T) {
+	h := NewHandlers(&fakeStore{
+		occupations: []Occupation{
+			{Code: "15-1252", Title: "Software Developers"},
+			{Code: "15-2031", Title: "Operations Research Analysts"},
+			{Code: "29-1141", Title: "Registered Nurses"},
+		},
+		groups: map[string]string{"15-1200": "Computer Occupations"},
+	})
+
+	rr := httptest.NewRecorder()
+	h.OccupationGroupsHandler(rr, httptest.NewRequest("GET", "/api/occupation-groups?level=minor", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var body struct {
+		Groups []OccupationGroup `json:"groups"`
+		Count  int               `json:"count"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Count != 2 || body.Groups[0].Title != "Computer and Mathematical Occupations" {
+		t.Fatalf("unexpected major groups: %+v", body.Groups)
+	}
+	minors := body.Groups[0].Children
+	if len(minors) != 2 || minors[0].Code != "15-1200" || minors[0].Title != "Computer Occupations" || minors[0].Children != nil {
+		t.Errorf("unexpected minor groups: %+v", minors)
+	}
+
+	rr = httptest.NewRecorder()
+	h.OccupationGroupsHandler(rr, httptest.NewRequest("GET", "/api/occupation-groups?level=sector", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown level, got %d", rr.Code)
+	}
+}
+
+func TestCalculateHandlerValidatesOccGroup(t *testing.T) {
+	store := &fakeStore{years: []int{2023}}
+	h := NewHandlers(store)
+
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Michigan&occGroup=15-0000", nil))
+	if rr.Code != http.StatusOK || store.lastFilters.OccGroup != "15-0000" {
+		t.Errorf("expected occGroup to reach the store, got %d %+v", rr.Code, store.lastFilters)
+	}
+
+	rr = httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Michigan&occGroup=computers", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for a malformed occGroup, got %d", rr.Code)
+	}
+}
+
+func TestCalculateHandlerMultipleOccupations(t *testing.T) {
+	h := NewHandlers(newTestMemoryStore(t))
+	calculate := func(query string) (int, CalculationResult) {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Michigan&"+query, nil))
+		var result CalculationResult
+		if rr.Code == http.StatusOK {
+			if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rr.Code, result
+	}
+
+	code, result := calculate("occupation=Registered+Nurses&occupation=Software+Developers")
+	if code != http.StatusOK || result.MatchingJobs != 140000 || len(result.Breakdown) != 2 {
+		t.Fatalf("expected combined 140000 with two breakdown entries, got %d %+v", code, result)
+	}
+	if b := result.Breakdown[1]; b.Occupation != "Software Developers" || b.MatchingJobs != 40000 || b.PercentageRegion != float64(40000)/float64(result.TotalJobsRegion)*100 {
+		t.Errorf("unexpected breakdown entry: %+v", b)
+	}
+
+	// Overlapping selections count shared rows once in the combined figures
+	_, result = calculate("occupation=Nurse&occCode=29-1141&occupation=Software&occCode=15-1252")
+	if result.MatchingJobs != 140000 || result.Breakdown[0].OccCode != "29-1141" || result.Breakdown[0].MatchingJobs != 100000 {
+		t.Errorf("expected title/code pairs, got %+v", result)
+	}
+	_, result = calculate("occupation=Nurse&occupation=Registered+Nurses")
+	if result.MatchingJobs != 102000 || result.Breakdown[0].MatchingJobs != 102000 || result.Breakdown[1].MatchingJobs != 100000 {
+		t.Errorf("expected de-duplicated combined rows, got %+v", result)
+	}
+
+	// A single selection keeps the original response shape
+	if _, result = calculate("occupation=Registered+Nurses"); result.Breakdown != nil {
+		t.Errorf("expected no breakdown for one occupation, got %+v", result.Breakdown)
+	}
+
+	if code, _ = calculate("occupation=Nurse&occupation=Software&occCode=29-1141"); code != http.StatusBadRequest {
+		t.Errorf("expected 400 for unpaired occupation/occCode, got %d", code)
+	}
+}
+
+func TestCalculateHandlerMinHourly(t *testing.T) {
+	store := &fakeStore{
+		rows: []careerRow{{
+			AreaTitle: "Testville, MI",
+			OccTitle:  "Actors",
+			TotEmp:    sql.NullFloat64{Float64: 500, Valid: true},
+			HMedian:   sql.NullFloat64{Float64: 23.5, Valid: true},
+		}},
+		national: 100000,
+		regional: map[string]int{"Testville, MI": 10000},
+		years:    []int{2023},
+	}
+	h := NewHandlers(store)
+
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Testville,+MI&minHourly=20", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var result CalculationResult
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if result.MatchingJobs != 500 || !result.MinSalaryMet || store.lastFilters.MinSalary != 41600 {
+		t.Errorf("expected minHourly=20 to act as a $41,600 threshold, got %+v (filters %+v)", result, store.lastFilters)
+	}
+	if result.SalaryInfo.MedianSalary != 48880 || !result.SalaryInfo.AnnualFromHourly || result.HourlyInfo.MedianHourly != 23.5 {
+		t.Errorf("unexpected wages: %+v / %+v", result.SalaryInfo, result.HourlyInfo)
+	}
+
+	for _, query := range []string{"minHourly=-5", "minHourly=abc", "minHourly=NaN", "minHourly=1e300", "minHourly=Inf", "minHourly=20&minSalary=50000"} {
+		rr := httptest.NewRecorder()
+		h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Testville,+MI&"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestCalculateHandlerReportsStatus(t *testing.T) {
+	priced := careerRow{AreaTitle: "Testville, MI", OccTitle: "Nurse", TotEmp: sql.NullFloat64{Float64: 500, Valid: true}, Median: sql.NullFloat64{Float64: 60000, Valid: true}}
+	unpriced := careerRow{AreaTitle: "Testville, MI", OccTitle: "Nurse", TotEmp: sql.NullFloat64{Float64: 500, Valid: true}}
+	for _, tc := range []struct {
+		name string
+		rows []careerRow
+		want string
+	}{
+		{"salary too low", []careerRow{priced}, statusMatched},
+		{"no rows", nil, statusNoRows},
+		{"no wages published", []careerRow{unpriced}, statusWageSuppressed},
+	} {
+		h := NewHandlers(&fakeStore{rows: tc.rows, national: 100000, regional: map[string]int{"Testville, MI": 10000}, years: []int{2023}})
+		rr := httptest.NewRecorder()
+		h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Testville,+MI&minSalary=90000", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.name, rr.Code, rr.Body)
+		}
+		var result CalculationResult
+		if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != tc.want || result.MatchingJobs != 0 || result.MinSalaryMet {
+			t.Errorf("%s: expected status %q with no matches, got %+v", tc.name, tc.want, result)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// readCPICSVFile opens and parses a CPI index CSV file
+func readCPICSVFile(path string) (map[int]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening CPI CSV: %v", err)
+	}
+	defer f.Close()
+
+	index, err := readCPICSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return index, nil
+}
+
+// readCPICSV parses a CSV with YEAR and CPI columns (matched by header name,
+// case-insensitive) into the annual average index keyed by year, e.g. the
+// BLS CPI-U annual averages. Rows with an empty CPI are skipped.
+func readCPICSV(r io.Reader) (map[int]float64, error) {
+	table, err := newCSVTable(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := table.require("YEAR", "CPI"); err != nil {
+		return nil, err
+	}
+
+	index := make(map[int]float64)
+	for {
+		rec, err := table.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rawYear, rawCPI := rec.text("YEAR"), rec.text("CPI")
+		if rawYear == "" || rawCPI == "" {
+			continue
+		}
+		year, err := strconv.Atoi(rawYear)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid YEAR %q", rec.line, rawYear)
+		}
+		cpi, err := strconv.ParseFloat(rawCPI, 64)
+		if err != nil || cpi <= 0 {
+			return nil, fmt.Errorf("line %d: invalid CPI %q", rec.line, rawCPI)
+		}
+		index[year] = cpi
+	}
+	return index, nil
+}
+
+// applyInflation returns copies of rows with every wage restated in year-to
+// dollars: wage × CPI(to) / CPI(row year). Every row year and to must have an
+// index; a missing one is reported as a requestError.
+func applyInflation(rows []careerRow, index map[int]float64, to int) ([]careerRow, error) {
+	target, ok := index[to]
+	if !ok {
+		return nil, requestError(fmt.Sprintf("no CPI index loaded for inflationTo year %d", to))
+	}
+	adjusted := make([]careerRow, len(rows))
+	for i, r := range rows {
+		base, ok := index[r.Year]
+		if !ok {
+			return nil, requestError(fmt.Sprintf("no CPI index loaded for data year %d", r.Year))
+		}
+		adjusted[i] = scaleWages(r, target/base)
+	}
+	return adjusted, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCPICSV(t *testing.T) {
+	index, err := readCPICSV(strings.NewReader("YEAR,CPI\n2022,292.655\n2023,304.702\n2024,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]float64{2022: 292.655, 2023: 304.702}; !reflect.DeepEqual(index, want) {
+		t.Errorf("expected %v, got %v", want, index)
+	}
+	for _, bad := range []string{"YEAR\n2023\n", "YEAR,CPI\nlast,300\n", "YEAR,CPI\n2023,-1\n"} {
+		if _, err := readCPICSV(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestApplyInflation(t *testing.T) {
+	index := map[int]float64{2021: 270, 2023: 300}
+	rows := []careerRow{
+		{Year: 2021, Median: sql.NullFloat64{Float64: 81000, Valid: true}},
+		{Year: 2023, Median: sql.NullFloat64{Float64: 90000, Valid: true}, TotEmp: sql.NullFloat64{Float64: 10, Valid: true}},
+	}
+	adjusted, err := applyInflation(rows, index, 2023)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adjusted[0].Median.Float64 != 90000 || adjusted[1].Median.Float64 != 90000 || adjusted[1].TotEmp.Float64 != 10 {
+		t.Errorf("unexpected constant-dollar rows %+v", adjusted)
+	}
+
+	var reqErr requestError
+	if _, err := applyInflation(rows, index, 2025); !errors.As(err, &reqErr) {
+		t.Errorf("expected a requestError for a target year without CPI, got %v", err)
+	}
+	if _, err := applyInflation(rows, map[int]float64{2023: 300}, 2023); !errors.As(err, &reqErr) {
+		t.Errorf("expected a requestError for a data year without CPI, got %v", err)
+	}
+}
+
+func TestCalculateHandlerInflationTo(t *testing.T) {
+	store := &fakeStore{
+		rows: []careerRow{{
+			Year:      2023,
+			AreaTitle: "Testville, MI",
+			OccTitle:  "Nurse",
+			TotEmp:    sql.NullFloat64{Float64: 500, Valid: true},
+			Median:    sql.NullFloat64{Float64: 90000, Valid: true},
+		}},
+		national: 100000,
+		regional: map[string]int{"Testville, MI": 10000},
+		years:    []int{2023},
+		cpi:      map[int]float64{2023: 300, 2025: 330},
+	}
+	h := NewHandlers(store)
+
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Testville,+MI&minSalary=95000&inflationTo=2025", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body)
+	}
+	var result CalculationResult
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	// $90k in 2023 is $99k in 2025 dollars, clearing a $95k target
+	if result.SalaryInfo.MedianSalary != 99000 || result.MatchingJobs != 500 || !result.MinSalaryMet || result.InflationTo != 2025 {
+		t.Errorf("unexpected constant-dollar result %+v", result)
+	}
+
+	for _, query := range []string{"inflationTo=2030", "inflationTo=25", "inflationTo=next"} {
+		rr := httptest.NewRecorder()
+		h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Testville,+MI&"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
+
+func TestUpsertCPIIndex(t *testing.T) {
+	ctx := context.Background()
+	lite := newTestSQLiteStore(t)
+	if err := upsertCPIIndex(ctx, lite.db, sqliteDialect, map[int]float64{2022: 290, 2023: 300}); err != nil {
+		t.Fatal(err)
+	}
+	if err := upsertCPIIndex(ctx, lite.db, sqliteDialect, map[int]float64{2023: 304.702}); err != nil {
+		t.Fatal(err)
+	}
+	index, err := lite.CPIIndex(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]float64{2022: 290, 2023: 304.702}; !reflect.DeepEqual(index, want) {
+		t.Errorf("expected %v, got %v", want, index)
+	}
+}
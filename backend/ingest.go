@@ -0,0 +1,477 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ingestReport summarizes what the ingest pipeline read, filtered and wrote
+type ingestReport struct {
+	Year                int
+	OEWSRowsRead        int
+	CrossIndustryRows   int
+	DetailedOrTotalRows int
+	DuplicatesDropped   int
+	MissingDropped      int
+	HourlyOnly          int
+	EducationRecords    int
+	EducationDuplicates int
+	EducationMatched    int
+	GroupTitles         int
+	Areas               int
+	Table               string
+	Inserted            int
+	Updated             int
+}
+
+// Print writes a human-readable summary
+func (r ingestReport) Print(w io.Writer) {
+	fmt.Fprintf(w, "OEWS release year:                     %d\n", r.Year)
+	fmt.Fprintf(w, "OEWS rows read:                        %d\n", r.OEWSRowsRead)
+	fmt.Fprintf(w, "  after cross-industry filter:         %d\n", r.CrossIndustryRows)
+	fmt.Fprintf(w, "  after detailed-or-00-0000 filter:    %d\n", r.DetailedOrTotalRows)
+	fmt.Fprintf(w, "  duplicate (AREA_TITLE, OCC_CODE):    %d dropped\n", r.DuplicatesDropped)
+	fmt.Fprintf(w, "  missing TOT_EMP or both medians:     %d dropped\n", r.MissingDropped)
+	fmt.Fprintf(w, "  hourly wages only:                   %d kept\n", r.HourlyOnly)
+	fmt.Fprintf(w, "Education records:                     %d (%d duplicates dropped)\n", r.EducationRecords, r.EducationDuplicates)
+	fmt.Fprintf(w, "Rows with education/experience match:  %d\n", r.EducationMatched)
+	fmt.Fprintf(w, "SOC group titles:                      %d\n", r.GroupTitles)
+	fmt.Fprintf(w, "Areas:                                 %d\n", r.Areas)
+	if r.Table == "" {
+		fmt.Fprintf(w, "Database:                              not written (dry run)\n")
+		return
+	}
+	fmt.Fprintf(w, "%-39s%d inserted, %d updated\n", r.Table+" upserted:", r.Inserted, r.Updated)
+}
+
+// educationRequirement is the EP Table 5.4 entry for an occupation
+type educationRequirement struct {
+	Education  string
+	Experience string
+}
+
+// runIngest implements the `ingest` subcommand: it reads the OEWS and EP
+// workbooks, applies the rules of processData.py / tableCombinationGenerator.py
+// and upserts the result, with the optional indexes, in a single transaction.
+func runIngest(args []string) error {
+	fs := flag.NewFlagSet("ingest", flag.ContinueOnError)
+	oewsPath := fs.String("oews", "", "path to the OEWS all_data_M_*.xlsx workbook (required)")
+	educationPath := fs.String("education", "", "path to the EP education workbook containing Table 5.4 (required)")
+	educationSheet := fs.String("education-sheet", "Table 5.4", "sheet name of the EP education table")
+	dataSource := fs.String("data-source", getEnv("DATA_SOURCE", "postgres"), "target database: postgres or sqlite")
+	sqlitePath := fs.String("sqlite-path", getEnv("SQLITE_PATH", "career_data.db"), "path to the sqlite database file")
+	year := fs.Int("year", 0, "OEWS release year (default: detected from the OEWS file name)")
+	history := fs.Bool("history", false, "load into career_data_history (trend data) instead of career_data")
+	csvOut := fs.String("csv-out", "", "optionally also write the combined dataset as CSV (memory data source format)")
+	dryRun := fs.Bool("dry-run", false, "process the workbooks and report without writing to the database")
+	rppPath := fs.String("rpp", "", "optional regional price parity CSV (AREA_TITLE, RPP) to load into regional_price_parities")
+	cpiPath := fs.String("cpi", "", "optional CPI index CSV (YEAR, CPI) to load into cpi_index")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	var batch ingestBatch
+	if err := batch.readIndexes(*rppPath, *cpiPath); err != nil {
+		return err
+	}
+	// The indexes may be loaded on their own, without career rows
+	if *oewsPath == "" && *educationPath == "" && (*rppPath != "" || *cpiPath != "") {
+		if !*dryRun {
+			if _, _, err := batch.write(*dataSource, *sqlitePath); err != nil {
+				return err
+			}
+		}
+		batch.printIndexes(os.Stdout, *rppPath, *cpiPath)
+		return nil
+	}
+	if *oewsPath == "" || *educationPath == "" {
+		fs.Usage()
+		return fmt.Errorf("both -oews and -education are required")
+	}
+
+	if *year == 0 {
+		if *year = yearFromOEWSFileName(*oewsPath); *year == 0 {
+			return fmt.Errorf("could not detect the release year from %s; pass -year", filepath.Base(*oewsPath))
+		}
+	}
+
+	var report ingestReport
+	report.Year = *year
+	rows, groups, err := readOEWSWorkbook(*oewsPath, *year, &report)
+	if err != nil {
+		return err
+	}
+	education, err := readEducationWorkbook(*educationPath, *educationSheet, &report)
+	if err != nil {
+		return err
+	}
+	report.EducationMatched = mergeEducation(rows, education)
+	batch.Table, batch.Rows, batch.Groups, batch.Areas = careerDataTable, rows, groups, areasFromRows(rows)
+	if *history {
+		batch.Table = careerHistoryTable
+	}
+	report.Areas = len(batch.Areas)
+
+	if *csvOut != "" {
+		if err := writeCareerCSVFile(*csvOut, rows); err != nil {
+			return err
+		}
+	}
+
+	if !*dryRun {
+		report.Table = batch.Table
+		if report.Inserted, report.Updated, err = batch.write(*dataSource, *sqlitePath); err != nil {
+			return err
+		}
+	}
+
+	report.Print(os.Stdout)
+	batch.printIndexes(os.Stdout, *rppPath, *cpiPath)
+	return nil
+}
+
+// ingestBatch is everything one ingest run writes: the career rows of Table
+// (none when Table is empty), SOC group titles, area metadata and the
+// optional price parities and CPI index
+type ingestBatch struct {
+	Table    string
+	Rows     []careerRow
+	Groups   []OccupationGroup
+	Areas    map[string]Area
+	Parities map[string]float64
+	CPI      map[int]float64
+}
+
+// readIndexes loads the optional regional price parity and CPI CSVs
+func (b *ingestBatch) readIndexes(rppPath, cpiPath string) error {
+	var err error
+	if rppPath != "" {
+		if b.Parities, err = readPriceParityCSVFile(rppPath); err != nil {
+			return err
+		}
+	}
+	if cpiPath != "" {
+		if b.CPI, err = readCPICSVFile(cpiPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write opens the target database and stores the batch in a single
+// transaction, so a failure part way leaves no half-loaded release. It
+// reports how many career rows were inserted versus updated.
+func (b ingestBatch) write(dataSource, sqlitePath string) (inserted, updated int, err error) {
+	db, d, err := openIngestDB(dataSource, sqlitePath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if b.Table != "" {
+			if inserted, updated, err = insertCareerRows(ctx, tx, d, b.Table, b.Rows); err != nil {
+				return err
+			}
+		}
+		if err := insertOccupationGroups(ctx, tx, d, b.Groups); err != nil {
+			return err
+		}
+		if err := insertAreas(ctx, tx, d, sortedAreas(b.Areas)); err != nil {
+			return err
+		}
+		if err := insertPriceParities(ctx, tx, d, b.Parities); err != nil {
+			return err
+		}
+		return insertCPIIndex(ctx, tx, d, b.CPI)
+	})
+	return inserted, updated, err
+}
+
+// printIndexes reports the price parities and CPI values the batch loaded
+func (b ingestBatch) printIndexes(w io.Writer, rppPath, cpiPath string) {
+	if rppPath != "" {
+		fmt.Fprintf(w, "%-39s%d areas from %s\n", "Regional price parities:", len(b.Parities), filepath.Base(rppPath))
+	}
+	if cpiPath != "" {
+		fmt.Fprintf(w, "%-39s%d years from %s\n", "CPI index:", len(b.CPI), filepath.Base(cpiPath))
+	}
+}
+
+// openIngestDB opens the target database and ensures the schema is current
+func openIngestDB(dataSource, sqlitePath string) (*sql.DB, dialect, error) {
+	switch strings.ToLower(dataSource) {
+	case "", "postgres":
+		db, err := initDB()
+		if err != nil {
+			return nil, dialect{}, err
+		}
+		if err := migrate(context.Background(), db, postgresDialect); err != nil {
+			db.Close()
+			return nil, dialect{}, err
+		}
+		return db, postgresDialect, nil
+	case "sqlite":
+		db, err := initSQLite(sqlitePath)
+		if err != nil {
+			return nil, dialect{}, err
+		}
+		return db, sqliteDialect, nil
+	default:
+		return nil, dialect{}, fmt.Errorf("ingest does not support data source %q", dataSource)
+	}
+}
+
+// oewsFileYear matches the release year in OEWS file names (all_data_M_2023.xlsx)
+var oewsFileYear = regexp.MustCompile(`all_data_M_(\d{4})`)
+
+// yearFromOEWSFileName returns the release year encoded in the file name, or 0
+func yearFromOEWSFileName(path string) int {
+	m := oewsFileYear.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return 0
+	}
+	year, _ := strconv.Atoi(m[1])
+	return year
+}
+
+// readOEWSWorkbook streams the first sheet of an OEWS all_data_M_*.xlsx
+// workbook for the given release year and keeps cross-industry rows that are
+// either detailed occupations or the '00-0000' total, deduplicated on
+// (AREA_TITLE, OCC_CODE) with the first occurrence winning. Rows missing
+// TOT_EMP, or missing both A_MEDIAN and H_MEDIAN, are dropped; hourly-only
+// rows are kept and their annual wages derived at query time. The titles of the major, minor and broad
+// SOC group rows are returned separately for occupation_groups.
+func readOEWSWorkbook(path string, year int, report *ingestReport) ([]careerRow, []OccupationGroup, error) {
+	f, err := excelize.OpenFile(path, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening OEWS workbook: %v", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, nil, fmt.Errorf("OEWS workbook %s has no sheets", path)
+	}
+	iter, err := f.Rows(sheets[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading OEWS sheet %q: %v", sheets[0], err)
+	}
+	defer iter.Close()
+
+	var cols map[string]int
+	seen := make(map[[2]string]bool)
+	var rows []careerRow
+	var groups []OccupationGroup
+	seenGroups := make(map[string]bool)
+	for iter.Next() {
+		cells, err := iter.Columns()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading OEWS row: %v", err)
+		}
+		if cols == nil {
+			cols = headerIndex(cells)
+			for _, required := range []string{"AREA_TITLE", "I_GROUP", "O_GROUP", "OCC_CODE", "OCC_TITLE", "TOT_EMP", "A_MEDIAN"} {
+				if _, ok := cols[required]; !ok {
+					return nil, nil, fmt.Errorf("OEWS workbook is missing column %s", required)
+				}
+			}
+			continue
+		}
+		cell := func(col string) string { return cellValue(cells, cols, col) }
+
+		report.OEWSRowsRead++
+		if cell("I_GROUP") != "cross-industry" {
+			continue
+		}
+		report.CrossIndustryRows++
+		switch level := cell("O_GROUP"); level {
+		case socMajor, socMinor, socBroad:
+			if code := cell("OCC_CODE"); !seenGroups[code] {
+				seenGroups[code] = true
+				groups = append(groups, OccupationGroup{Code: code, Title: cell("OCC_TITLE"), Level: level})
+			}
+		}
+		if cell("O_GROUP") != "detailed" && cell("OCC_CODE") != "00-0000" {
+			continue
+		}
+		report.DetailedOrTotalRows++
+
+		key := [2]string{cell("AREA_TITLE"), cell("OCC_CODE")}
+		if seen[key] {
+			report.DuplicatesDropped++
+			continue
+		}
+		seen[key] = true
+
+		row := careerRow{
+			Year:      year,
+			AreaCode:  cell("AREA"),
+			AreaTitle: key[0],
+			AreaType:  cell("AREA_TYPE"),
+			PrimState: cell("PRIM_STATE"),
+			OccCode:   key[1],
+			OccTitle:  cell("OCC_TITLE"),
+			TotEmp:    parseWorkbookNumber(cell("TOT_EMP")),
+		}
+		// '#' marks wages at or above the top-code cap, stored as the cap
+		row.readWages(cell, func(_, raw string) (sql.NullFloat64, error) { return parseWorkbookNumber(raw), nil })
+		// Occupations paid by the hour (actors, some healthcare roles) publish
+		// only hourly wages; keep them and derive annual figures at query time
+		if !row.TotEmp.Valid || (!row.Median.Valid && !row.HMedian.Valid) {
+			report.MissingDropped++
+			continue
+		}
+		if !row.Median.Valid {
+			report.HourlyOnly++
+		}
+		rows = append(rows, row)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, fmt.Errorf("error reading OEWS workbook: %v", err)
+	}
+	if cols == nil {
+		return nil, nil, fmt.Errorf("OEWS workbook %s is empty", path)
+	}
+	report.GroupTitles = len(groups)
+	return rows, groups, nil
+}
+
+// readEducationWorkbook reads OCC_CODE → education/experience from the EP
+// Table 5.4 sheet. The header row sits below a title row; the occupation code
+// column is named after the projection year ("2023 National Employment Matrix code").
+func readEducationWorkbook(path, sheet string, report *ingestReport) (map[string]educationRequirement, error) {
+	f, err := excelize.OpenFile(path, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return nil, fmt.Errorf("error opening education workbook: %v", err)
+	}
+	defer f.Close()
+
+	if idx, err := f.GetSheetIndex(sheet); err != nil || idx < 0 {
+		return nil, fmt.Errorf("could not find sheet %q in %s", sheet, path)
+	}
+	all, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, fmt.Errorf("error reading sheet %q: %v", sheet, err)
+	}
+
+	codeCol, eduCol, expCol := -1, -1, -1
+	start := 0
+	for i, cells := range all {
+		for j, c := range cells {
+			name := strings.TrimSpace(c)
+			switch {
+			case strings.HasSuffix(name, "National Employment Matrix code"):
+				codeCol = j
+			case name == "Typical education needed for entry":
+				eduCol = j
+			case name == "Work experience in a related occupation":
+				expCol = j
+			}
+		}
+		if codeCol >= 0 {
+			start = i + 1
+			break
+		}
+	}
+	if codeCol < 0 || eduCol < 0 || expCol < 0 {
+		return nil, fmt.Errorf("sheet %q does not contain the expected National Employment Matrix code, education and experience columns", sheet)
+	}
+
+	education := make(map[string]educationRequirement)
+	for _, cells := range all[start:] {
+		at := func(j int) string {
+			if j < len(cells) {
+				return strings.TrimSpace(cells[j])
+			}
+			return ""
+		}
+		code := at(codeCol)
+		if code == "" {
+			continue
+		}
+		if _, dup := education[code]; dup {
+			report.EducationDuplicates++
+			continue
+		}
+		education[code] = educationRequirement{Education: at(eduCol), Experience: at(expCol)}
+	}
+	report.EducationRecords = len(education)
+	return education, nil
+}
+
+// mergeEducation left-joins education/experience onto rows by OCC_CODE and
+// returns the number of rows that found a match
+func mergeEducation(rows []careerRow, education map[string]educationRequirement) int {
+	matched := 0
+	for i := range rows {
+		if req, ok := education[rows[i].OccCode]; ok {
+			rows[i].Education = req.Education
+			rows[i].Experience = req.Experience
+			matched++
+		}
+	}
+	return matched
+}
+
+// cellValue returns the trimmed value of a named column, or "" if absent
+func cellValue(cells []string, cols map[string]int, col string) string {
+	if i, ok := cols[col]; ok && i < len(cells) {
+		return strings.TrimSpace(cells[i])
+	}
+	return ""
+}
+
+// parseWorkbookNumber converts a raw cell to a nullable number, treating the
+// OEWS symbols '*' and '**' (and anything non-numeric) as missing. Callers
+// store '#' wages at the top-code cap before this function sees them.
+func parseWorkbookNumber(v string) sql.NullFloat64 {
+	if isMissingValue(v) {
+		return sql.NullFloat64{}
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", ""), 64)
+	if err != nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: f, Valid: true}
+}
+
+// writeCareerCSVFile writes rows in the combined_career_data.csv layout
+func writeCareerCSVFile(path string, rows []careerRow) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", path, err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	header := []string{"DATA_YEAR", "AREA", "AREA_TITLE", "AREA_TYPE", "PRIM_STATE", "OCC_CODE", "OCC_TITLE", "Education", "Experience", "TOT_EMP"}
+	header = append(header, annualWageColumns[:]...)
+	w.Write(append(header, hourlyWageColumns[:]...))
+	num := func(v sql.NullFloat64) string {
+		if !v.Valid {
+			return ""
+		}
+		return strconv.FormatFloat(v.Float64, 'f', -1, 64)
+	}
+	for _, r := range rows {
+		record := []string{strconv.Itoa(r.Year), r.AreaCode, r.AreaTitle, r.AreaType, r.PrimState, r.OccCode, r.OccTitle, r.Education, r.Experience, num(r.TotEmp)}
+		w.Write(append(record, r.writeWages(num)...))
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeTestWorkbook saves rows to a new workbook, renaming the default sheet
+func writeTestWorkbook(t *testing.T, path, sheet string, rows [][]interface{}) {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	if sheet != "Sheet1" {
+		if err := f.SetSheetName("Sheet1", sheet); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestIngestWorkbooks(t *testing.T) (oewsPath, educationPath string) {
+	t.Helper()
+	dir := t.TempDir()
+	oewsPath = filepath.Join(dir, "all_data_M_2023.xlsx")
+	educationPath = filepath.Join(dir, "education.xlsx")
+
+	header := []interface{}{"AREA", "AREA_TITLE", "AREA_TYPE", "PRIM_STATE", "I_GROUP", "OCC_CODE", "OCC_TITLE", "O_GROUP",
+		"TOT_EMP", "A_PCT10", "A_PCT25", "A_MEDIAN", "A_PCT75", "A_PCT90", "H_MEDIAN"}
+	writeTestWorkbook(t, oewsPath, "All May 2023 data", [][]interface{}{
+		header,
+		{"26", "Michigan", 2, "MI", "cross-industry", "00-0000", "All Occupations", "total", 4300000, 28000, 34000, 47000, 75000, 115000, 22.6},
+		{"26", "Michigan", 2, "MI", "cross-industry", "15-0000", "Computer and Mathematical Occupations", "major", 90000, 50000, 70000, 95000, 120000, 150000, 45.67},
+		{"26", "Michigan", 2, "MI", "cross-industry", "15-1252", "Software Developers", "detailed", 40000, 70000, 88000, 105000, 130000, "#", 50.48},
+		{"26", "Michigan", 2, "MI", "cross-industry", "15-1252", "Software Developers", "detailed", 1, 1, 1, 1, 1, 1, 1},
+		{"26", "Michigan", 2, "MI", "sector", "15-1252", "Software Developers", "detailed", 5000, 70000, 88000, 105000, 130000, 160000, 50.48},
+		{"26", "Michigan", 2, "MI", "cross-industry", "29-1141", "Registered Nurses", "detailed", 100000, 64000, 75000, 86000, 99000, 110000, 41.35},
+		{"26", "Michigan", 2, "MI", "cross-industry", "27-2011", "Actors", "detailed", 500, "*", "*", "*", "*", "*", 23.5},
+		{"26", "Michigan", 2, "MI", "cross-industry", "27-2031", "Dancers", "detailed", 300, "*", "*", "*", "*", "*", "*"},
+		{"26", "Michigan", 2, "MI", "cross-industry", "27-2012", "Producers and Directors", "detailed", "**", 40000, 50000, 60000, 70000, 80000, 28.85},
+	})
+	writeTestWorkbook(t, educationPath, "Table 5.4", [][]interface{}{
+		{"Table 5.4 Education and training assignments by detailed occupation, 2023"},
+		{"2023 National Employment Matrix title", "2023 National Employment Matrix code", "Typical education needed for entry", "Work experience in a related occupation", "Typical on-the-job training needed to attain competency in the occupation"},
+		{"Software developers", "15-1252", "Bachelor's degree", "None", "None"},
+		{"Software developers", "15-1252", "Master's degree", "None", "None"},
+		{"Registered nurses", "29-1141", "Bachelor's degree", "None", "None"},
+	})
+	return oewsPath, educationPath
+}
+
+func TestReadOEWSWorkbookAppliesPipelineRules(t *testing.T) {
+	oewsPath, educationPath := writeTestIngestWorkbooks(t)
+
+	var report ingestReport
+	rows, groups, err := readOEWSWorkbook(oewsPath, 2023, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 || groups[0].Code != "15-0000" || groups[0].Level != socMajor || groups[0].Title != "Computer and Mathematical Occupations" {
+		t.Errorf("unexpected group titles: %+v", groups)
+	}
+	if report.OEWSRowsRead != 9 || report.CrossIndustryRows != 8 || report.DetailedOrTotalRows != 7 {
+		t.Errorf("unexpected filter counts: %+v", report)
+	}
+	if report.DuplicatesDropped != 1 || report.MissingDropped != 2 || report.HourlyOnly != 1 {
+		t.Errorf("unexpected drop counts: %+v", report)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d: %+v", len(rows), rows)
+	}
+
+	// Actors publish hourly wages only and are kept without annual figures
+	if actors := rows[3]; actors.OccCode != "27-2011" || actors.Median.Valid || actors.HMedian.Float64 != 23.5 {
+		t.Errorf("unexpected hourly-only row: %+v", actors)
+	}
+
+	// First occurrence wins and '#' is stored as the top-code cap with its flag
+	dev := rows[1]
+	if dev.OccCode != "15-1252" || dev.TotEmp.Float64 != 40000 || dev.Pct90.Float64 != topCodeAnnual || dev.TopCoded != 1<<4 {
+		t.Errorf("unexpected software developer row: %+v", dev)
+	}
+
+	// AREA_TYPE and PRIM_STATE feed the areas table
+	if areas := areasFromRows(rows); !reflect.DeepEqual(areas, map[string]Area{"Michigan": {Code: "26", Title: "Michigan", Type: areaTypeState, States: []string{"MI"}}}) {
+		t.Errorf("unexpected areas: %+v", areas)
+	}
+
+	education, err := readEducationWorkbook(educationPath, "Table 5.4", &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.EducationRecords != 2 || report.EducationDuplicates != 1 {
+		t.Errorf("unexpected education counts: %+v", report)
+	}
+	if matched := mergeEducation(rows, education); matched != 2 {
+		t.Errorf("expected 2 education matches, got %d", matched)
+	}
+	if rows[1].Education != "Bachelor's degree" || rows[0].Education != "" {
+		t.Errorf("unexpected merge result: %+v / %+v", rows[0], rows[1])
+	}
+
+	if _, err := readEducationWorkbook(educationPath, "Table 9.9", &report); err == nil {
+		t.Error("expected an error for a missing sheet")
+	}
+}
+
+func TestUpsertCareerRowsReportsInsertsAndUpdates(t *testing.T) {
+	oewsPath, educationPath := writeTestIngestWorkbooks(t)
+	var report ingestReport
+	rows, groups, err := readOEWSWorkbook(oewsPath, 2023, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	education, err := readEducationWorkbook(educationPath, "Table 5.4", &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergeEducation(rows, education)
+
+	db, err := initSQLite(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	if err := upsertOccupationGroups(ctx, db, sqliteDialect, groups); err != nil {
+		t.Fatal(err)
+	}
+	if titles, _ := NewSQLiteStore(db).OccupationGroupTitles(ctx); titles["15-0000"] != "Computer and Mathematical Occupations" {
+		t.Errorf("unexpected stored group titles: %v", titles)
+	}
+
+	inserted, updated, err := upsertCareerRows(ctx, db, sqliteDialect, careerDataTable, rows)
+	if err != nil || inserted != 4 || updated != 0 {
+		t.Fatalf("first upsert: inserted=%d updated=%d err=%v", inserted, updated, err)
+	}
+
+	rows[1].Median.Float64 = 110000
+	inserted, updated, err = upsertCareerRows(ctx, db, sqliteDialect, careerDataTable, rows)
+	if err != nil || inserted != 0 || updated != 4 {
+		t.Fatalf("second upsert: inserted=%d updated=%d err=%v", inserted, updated, err)
+	}
+
+	var median int
+	var edu string
+	if err := db.QueryRow("SELECT a_median, education FROM career_data WHERE occ_code = '15-1252'").Scan(&median, &edu); err != nil {
+		t.Fatal(err)
+	}
+	if median != 110000 || edu != "Bachelor's degree" {
+		t.Errorf("unexpected stored row: median=%d education=%q", median, edu)
+	}
+
+	stored, err := NewSQLiteStore(db).MatchingRows(ctx, Filters{Location: "Michigan", OccCode: "27-2011"})
+	if err != nil || len(stored) != 1 || stored[0].Median.Valid || stored[0].HMedian.Float64 != 23.5 {
+		t.Errorf("expected the hourly-only row to round-trip, got %+v (%v)", stored, err)
+	}
+}
+
+func TestIngestBatchWritesAllOrNothing(t *testing.T) {
+	oewsPath, educationPath := writeTestIngestWorkbooks(t)
+	var report ingestReport
+	rows, groups, err := readOEWSWorkbook(oewsPath, 2023, &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	education, err := readEducationWorkbook(educationPath, "Table 5.4", &report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergeEducation(rows, education)
+
+	// Dropping cpi_index makes the last write of the batch fail
+	sqlitePath := filepath.Join(t.TempDir(), "career_data.db")
+	db, err := initSQLite(sqlitePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("DROP TABLE cpi_index"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	batch := ingestBatch{Table: careerDataTable, Rows: rows, Groups: groups, Areas: areasFromRows(rows), CPI: map[int]float64{2023: 304.702}}
+	if _, _, err := batch.write("sqlite", sqlitePath); err == nil {
+		t.Fatal("expected the batch to fail without cpi_index")
+	}
+	db, err = initSQLite(sqlitePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, table := range []string{"career_data", "occupation_groups", "areas"} {
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&n); err != nil || n != 0 {
+			t.Errorf("%s: expected the failed batch to be rolled back, got %d rows (%v)", table, n, err)
+		}
+	}
+
+	if _, err := db.Exec("CREATE TABLE cpi_index (year INTEGER PRIMARY KEY, cpi DOUBLE PRECISION NOT NULL)"); err != nil {
+		t.Fatal(err)
+	}
+	inserted, _, err := batch.write("sqlite", sqlitePath)
+	if err != nil || inserted != len(rows) {
+		t.Fatalf("expected %d rows inserted, got %d (%v)", len(rows), inserted, err)
+	}
+	if index, _ := NewSQLiteStore(db).CPIIndex(context.Background()); index[2023] != 304.702 {
+		t.Errorf("expected the CPI index to be written with the rows, got %v", index)
+	}
+}
@@ -2,10 +2,14 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -21,26 +25,68 @@ func main() {
 		log.Println("Warning: .env file not found, using system environment variables")
 	}
 
-	// Initialize database connection
-	db, err := initDB()
+	// Subcommands run instead of the API server
+	if len(os.Args) > 1 && os.Args[1] == "ingest" {
+		if err := runIngest(os.Args[2:]); err != nil {
+			log.Fatal("Ingest failed: ", err)
+		}
+		return
+	}
+
+	// Select the data source: "postgres" (default), "sqlite" (embedded file) or "memory" (CSV loaded at startup)
+	var cfg storeConfig
+	flag.StringVar(&cfg.DataSource, "data-source", getEnv("DATA_SOURCE", "postgres"), "career data backend: postgres, sqlite or memory")
+	flag.StringVar(&cfg.CSVPath, "csv", getEnv("CAREER_DATA_CSV", "combined_career_data.csv"), "path to combined_career_data.csv for the memory data source (also seeds an empty sqlite database)")
+	flag.StringVar(&cfg.HistoryCSVPath, "history-csv", getEnv("CAREER_HISTORY_CSV", ""), "optional CSV of past releases (with DATA_YEAR) for /api/trend on the memory data source")
+	flag.StringVar(&cfg.RPPCSVPath, "rpp-csv", getEnv("RPP_CSV", ""), "optional regional price parity CSV (AREA_TITLE, RPP) for adjust=rpp on the memory data source")
+	flag.StringVar(&cfg.CPICSVPath, "cpi-csv", getEnv("CPI_CSV", ""), "optional CPI index CSV (YEAR, CPI) for inflationTo on the memory data source")
+	flag.StringVar(&cfg.SQLitePath, "sqlite-path", getEnv("SQLITE_PATH", "career_data.db"), "path to the sqlite database file")
+	zipCrosswalkPath := flag.String("zip-crosswalk", getEnv("ZIP_CROSSWALK_CSV", ""), "optional ZIP crosswalk CSV (ZIP, COUNTY, CBSA, CITY, STATE[, AREA]) for /api/resolve-area and zip on /api/calculate")
+	centroidsPath := flag.String("area-centroids", getEnv("AREA_CENTROIDS_CSV", ""), "optional area centroid CSV (AREA_TITLE or AREA, LAT, LNG) for radiusMiles on /api/calculate")
+	flag.Parse()
+	cfg.MigratePostgres = getEnv("DB_MIGRATE", "") == "true"
+
+	store, closeStore, err := openStore(cfg)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		log.Fatal("Failed to initialize data store:", err)
 	}
-	defer db.Close()
+	defer closeStore()
 
 	// Initialize router
 	r := mux.NewRouter()
 
-	// Initialize handlers with database connection
-	handlers := NewHandlers(db)
+	// Initialize handlers with the selected store
+	handlers := NewHandlers(store)
+	handlers.compareLimits = CompareLimits{
+		MaxLocations: getEnvInt("COMPARE_MAX_LOCATIONS", defaultCompareLimits.MaxLocations),
+		Workers:      getEnvInt("COMPARE_WORKERS", defaultCompareLimits.Workers),
+	}
+	if *zipCrosswalkPath != "" {
+		if handlers.zips, err = readZIPCrosswalkCSVFile(*zipCrosswalkPath); err != nil {
+			log.Fatal("Failed to load ZIP crosswalk:", err)
+		}
+	}
+	if *centroidsPath != "" {
+		if handlers.centroids, err = readAreaCentroidsCSVFile(*centroidsPath); err != nil {
+			log.Fatal("Failed to load area centroids:", err)
+		}
+	}
 
 	// API routes
 	api := r.PathPrefix("/api").Subrouter()
 	api.HandleFunc("/calculate", handlers.CalculateHandler).Methods("GET")
+	api.HandleFunc("/compare", handlers.CompareHandler).Methods("GET")
+	api.HandleFunc("/rank/areas", handlers.RankAreasHandler).Methods("GET")
+	api.HandleFunc("/rank/occupations", handlers.RankOccupationsHandler).Methods("GET")
+	api.HandleFunc("/trend", handlers.TrendHandler).Methods("GET")
+	api.HandleFunc("/years", handlers.YearsHandler).Methods("GET")
 	api.HandleFunc("/occupations", handlers.OccupationsHandler).Methods("GET")
+	api.HandleFunc("/occupation-groups", handlers.OccupationGroupsHandler).Methods("GET")
 	api.HandleFunc("/locations", handlers.LocationsHandler).Methods("GET")
 	api.HandleFunc("/states", handlers.StatesHandler).Methods("GET")
 	api.HandleFunc("/areas-by-state", handlers.AreasByStateHandler).Methods("GET")
+	api.HandleFunc("/areas", handlers.AreasHandler).Methods("GET")
+	api.HandleFunc("/resolve-area", handlers.ResolveAreaHandler).Methods("GET")
 	api.HandleFunc("/health", handlers.HealthHandler).Methods("GET")
 
 	// Attach rate limiter (100 req/min/IP)
@@ -89,6 +135,89 @@ func main() {
 	log.Println("Server exiting")
 }
 
+// storeConfig selects and configures the career data backend
+type storeConfig struct {
+	DataSource      string
+	CSVPath         string
+	HistoryCSVPath  string
+	RPPCSVPath      string
+	CPICSVPath      string
+	SQLitePath      string
+	MigratePostgres bool
+}
+
+// openStore creates the CareerDataStore for the requested data source.
+// The returned close function releases any underlying resources.
+func openStore(cfg storeConfig) (CareerDataStore, func() error, error) {
+	switch strings.ToLower(cfg.DataSource) {
+	case "", "postgres":
+		db, err := initDB()
+		if err != nil {
+			return nil, nil, err
+		}
+		// Without DB_MIGRATE the schema must already be current
+		check := checkSchema
+		if cfg.MigratePostgres {
+			check = func(ctx context.Context, db *sql.DB) error { return migrate(ctx, db, postgresDialect) }
+		}
+		if err := check(context.Background(), db); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return NewPostgresStore(db), db.Close, nil
+	case "sqlite":
+		db, err := initSQLite(cfg.SQLitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := seedSQLiteFromCSV(context.Background(), db, cfg.CSVPath); err != nil {
+			db.Close()
+			return nil, nil, err
+		}
+		return NewSQLiteStore(db), db.Close, nil
+	case "memory":
+		store, err := LoadMemoryStore(cfg.CSVPath, cfg.HistoryCSVPath, cfg.RPPCSVPath, cfg.CPICSVPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		log.Printf("Loaded %d career data rows from %s", len(store.rows), cfg.CSVPath)
+		return store, func() error { return nil }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown data source %q", cfg.DataSource)
+	}
+}
+
+// seedSQLiteFromCSV imports the combined career data CSV into an empty
+// SQLite database so a fresh demo binary is usable out of the box
+func seedSQLiteFromCSV(ctx context.Context, db *sql.DB, csvPath string) error {
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM career_data").Scan(&count); err != nil {
+		return fmt.Errorf("error counting career_data rows: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	f, err := os.Open(csvPath)
+	if err != nil {
+		log.Printf("Warning: sqlite database is empty and %s could not be opened: %v", csvPath, err)
+		return nil
+	}
+	defer f.Close()
+
+	rows, err := readCareerCSV(f)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", csvPath, err)
+	}
+	if _, _, err := upsertCareerRows(ctx, db, sqliteDialect, careerDataTable, rows); err != nil {
+		return err
+	}
+	if err := upsertAreas(ctx, db, sqliteDialect, areasFromRows(rows)); err != nil {
+		return fmt.Errorf("error seeding areas: %v", err)
+	}
+	log.Printf("Seeded sqlite database with %d rows from %s", len(rows), csvPath)
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -96,6 +225,21 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt reads a positive integer environment variable, falling back to
+// defaultValue when it is unset or invalid
+func getEnvInt(key string, defaultValue int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return defaultValue
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 1 {
+		log.Printf("Warning: ignoring invalid %s=%q, using %d", key, raw, defaultValue)
+		return defaultValue
+	}
+	return v
+}
+
 // getAllowedOrigins parses CORS_ORIGIN which may be a comma-separated list
 // Defaults to allowing Vite ports 5173 and 5174 for local dev
 func getAllowedOrigins() []string {
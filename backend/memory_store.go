@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// careerRow mirrors a single career_data row (one occupation in one area for a data year)
+type careerRow struct {
+	Year       int
+	AreaCode   string // OEWS AREA code; empty means NULL
+	AreaTitle  string
+	AreaType   string // OEWS AREA_TYPE code, kept in the areas table only
+	PrimState  string // OEWS PRIM_STATE abbreviation, kept in the areas table only
+	OccCode    string
+	OccTitle   string
+	Education  string // empty means NULL
+	Experience string // empty means NULL
+	TotEmp     sql.NullFloat64
+	Median     sql.NullFloat64
+	Pct10      sql.NullFloat64
+	Pct25      sql.NullFloat64
+	Pct75      sql.NullFloat64
+	Pct90      sql.NullFloat64
+	// Hourly wage percentiles; occupations such as actors publish only these
+	HMedian sql.NullFloat64
+	HPct10  sql.NullFloat64
+	HPct25  sql.NullFloat64
+	HPct75  sql.NullFloat64
+	HPct90  sql.NullFloat64
+	// TopCoded and HTopCoded flag annual and hourly percentiles published as
+	// the OEWS top-code cap: bit i refers to rowPercentiles(r)[i]
+	TopCoded  uint8
+	HTopCoded uint8
+	// PriceParity is the regional price parity the wages were deflated by
+	// (applyPriceParities); it is never stored
+	PriceParity sql.NullFloat64
+}
+
+// careerKey identifies a career_data row
+type careerKey struct {
+	Year      int
+	AreaTitle string
+	OccCode   string
+}
+
+func (r careerRow) key() careerKey {
+	return careerKey{Year: r.Year, AreaTitle: r.AreaTitle, OccCode: r.OccCode}
+}
+
+// MemoryStore implements CareerDataStore over rows held in memory.
+// It mirrors the semantics of SQLStore so the API answers identically
+// without a database (CI, local development).
+type MemoryStore struct {
+	rows    []careerRow
+	history []careerRow // career_data_history equivalent for trends
+	groups  map[string]string
+	// parities holds regional price parities keyed by area title
+	parities map[string]float64
+	// cpi holds the annual CPI index keyed by year
+	cpi map[int]float64
+	// areas holds the area metadata of rows and history keyed by area title
+	areas map[string]Area
+}
+
+// NewMemoryStore creates a MemoryStore over the given rows
+func NewMemoryStore(rows []careerRow) *MemoryStore {
+	return &MemoryStore{rows: rows, areas: areasFromRows(rows)}
+}
+
+// LoadMemoryStore reads combined_career_data.csv (as produced by the
+// data-processing pipeline) into a MemoryStore. historyPath optionally names a
+// CSV in the same layout (with DATA_YEAR) holding past releases for trends and
+// rppPath and cpiPath optional regional price parity (AREA_TITLE, RPP) and
+// CPI (YEAR, CPI) CSVs.
+func LoadMemoryStore(path, historyPath, rppPath, cpiPath string) (*MemoryStore, error) {
+	rows, err := readCareerCSVFile(path)
+	if err != nil {
+		return nil, err
+	}
+	store := NewMemoryStore(rows)
+	if historyPath != "" {
+		if store.history, err = readCareerCSVFile(historyPath); err != nil {
+			return nil, err
+		}
+		store.areas = areasFromRows(store.rows, store.history)
+	}
+	if rppPath != "" {
+		if store.parities, err = readPriceParityCSVFile(rppPath); err != nil {
+			return nil, err
+		}
+	}
+	if cpiPath != "" {
+		if store.cpi, err = readCPICSVFile(cpiPath); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// readCareerCSVFile opens and parses a career data CSV file
+func readCareerCSVFile(path string) ([]careerRow, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening career data CSV: %v", err)
+	}
+	defer f.Close()
+
+	rows, err := readCareerCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return rows, nil
+}
+
+// readCareerCSV parses the combined career data CSV. Columns are matched by
+// header name (case-insensitive); empty cells and OEWS symbols become NULL.
+// Files without a DATA_YEAR column are assigned defaultDataYear.
+func readCareerCSV(r io.Reader) ([]careerRow, error) {
+	table, err := newCSVTable(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := table.require("AREA_TITLE", "OCC_CODE"); err != nil {
+		return nil, err
+	}
+
+	var rows []careerRow
+	for {
+		rec, err := table.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		text, line := rec.text, rec.line
+		num := func(col string) (sql.NullFloat64, error) {
+			v := text(col)
+			if isMissingValue(v) {
+				return sql.NullFloat64{}, nil
+			}
+			f, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", ""), 64)
+			if err != nil {
+				return sql.NullFloat64{}, fmt.Errorf("line %d: invalid %s %q", line, col, v)
+			}
+			return sql.NullFloat64{Float64: f, Valid: true}, nil
+		}
+
+		year := defaultDataYear
+		if v := text("DATA_YEAR"); v != "" {
+			if year, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("line %d: invalid DATA_YEAR %q", line, v)
+			}
+		}
+
+		row := careerRow{
+			Year:       year,
+			AreaCode:   text("AREA"),
+			AreaTitle:  text("AREA_TITLE"),
+			AreaType:   text("AREA_TYPE"),
+			PrimState:  text("PRIM_STATE"),
+			OccCode:    text("OCC_CODE"),
+			OccTitle:   text("OCC_TITLE"),
+			Education:  text("EDUCATION"),
+			Experience: text("EXPERIENCE"),
+		}
+		if row.TotEmp, err = num("TOT_EMP"); err != nil {
+			return nil, err
+		}
+		if err := row.readWages(text, func(col, _ string) (sql.NullFloat64, error) { return num(col) }); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// isMissingValue reports whether a raw cell should be treated as NULL.
+// OEWS uses '*', '**' and '#' for suppressed or unavailable values.
+func isMissingValue(v string) bool {
+	switch v {
+	case "", "*", "**", "#", "NaN", "nan", "<NA>":
+		return true
+	}
+	return false
+}
+
+// ListOccupations returns distinct occupation code/title pairs, excluding the aggregate row
+func (s *MemoryStore) ListOccupations(ctx context.Context) ([]Occupation, error) {
+	seen := make(map[Occupation]struct{})
+	var occupations []Occupation
+	for _, r := range s.rows {
+		o := Occupation{Code: r.OccCode, Title: r.OccTitle}
+		if o.Title == "" || o.Title == "All Occupations" {
+			continue
+		}
+		if _, dup := seen[o]; dup {
+			continue
+		}
+		seen[o] = struct{}{}
+		occupations = append(occupations, o)
+	}
+	sort.Slice(occupations, func(i, j int) bool {
+		if occupations[i].Title != occupations[j].Title {
+			return occupations[i].Title < occupations[j].Title
+		}
+		return occupations[i].Code < occupations[j].Code
+	})
+	return occupations, nil
+}
+
+// ListAreas returns distinct area titles, excluding national areas
+func (s *MemoryStore) ListAreas(ctx context.Context) ([]string, error) {
+	return s.distinct(func(r careerRow) (string, bool) {
+		return r.AreaTitle, r.AreaTitle != "" && s.areas[r.AreaTitle].Type != areaTypeNational
+	}), nil
+}
+
+// ListStates returns distinct state-level area titles
+func (s *MemoryStore) ListStates(ctx context.Context) ([]string, error) {
+	return s.distinct(func(r careerRow) (string, bool) {
+		return r.AreaTitle, s.areas[r.AreaTitle].Type == areaTypeState
+	}), nil
+}
+
+// AreasForState returns all area titles relevant to a given state: the state
+// itself and every area listing it among its member states. Unknown states
+// match nothing.
+func (s *MemoryStore) AreasForState(ctx context.Context, state string) ([]string, error) {
+	state, abbr, ok := lookupState(state)
+	if !ok {
+		return nil, nil
+	}
+	return s.distinct(func(r careerRow) (string, bool) {
+		a := r.AreaTitle
+		return a, a == state || containsString(s.areas[a].States, abbr)
+	}), nil
+}
+
+// Areas returns the area metadata derived from the loaded rows
+func (s *MemoryStore) Areas(ctx context.Context) (map[string]Area, error) {
+	return s.areas, nil
+}
+
+// MatchingRows returns the rows satisfying the same filters as buildQuery
+// (the salary threshold is applied later by aggregateRows)
+func (s *MemoryStore) MatchingRows(ctx context.Context, filters Filters) ([]careerRow, error) {
+	filters.MinSalary = 0
+	var rows []careerRow
+	for _, r := range s.rows {
+		if rowMatchesFilters(r, filters) {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+// HistoryRows returns the history rows satisfying the filters for any year
+func (s *MemoryStore) HistoryRows(ctx context.Context, filters Filters) ([]careerRow, error) {
+	filters.Year = 0
+	filters.MinSalary = 0
+	var rows []careerRow
+	for _, r := range s.history {
+		if rowMatchesFilters(r, filters) {
+			rows = append(rows, r)
+		}
+	}
+	return rows, nil
+}
+
+// nationalRow returns the '00-0000' row of the year with the largest tot_emp
+func (s *MemoryStore) nationalRow(year int) (careerRow, bool) {
+	var national careerRow
+	found := false
+	for _, r := range s.rows {
+		if r.OccCode == "00-0000" && r.Year == year && r.TotEmp.Valid && (!found || r.TotEmp.Float64 > national.TotEmp.Float64) {
+			national = r
+			found = true
+		}
+	}
+	return national, found
+}
+
+// NationalTotal returns the largest tot_emp among '00-0000' rows of the year
+func (s *MemoryStore) NationalTotal(ctx context.Context, year int) (int, error) {
+	national, ok := s.nationalRow(year)
+	if !ok {
+		return 0, fmt.Errorf("error querying total jobs: %v", sql.ErrNoRows)
+	}
+	return int(national.TotEmp.Float64), nil
+}
+
+// NationalArea returns the area title of the row NationalTotal reads
+func (s *MemoryStore) NationalArea(ctx context.Context, year int) (string, error) {
+	national, _ := s.nationalRow(year)
+	return national.AreaTitle, nil
+}
+
+// RegionalTotal returns the summed detailed-occupation employment of the
+// areas selected by the location filters
+func (s *MemoryStore) RegionalTotal(ctx context.Context, filters Filters) (int, error) {
+	scope := filters.regionScope()
+	var total nullAccumulator
+	for _, r := range s.rows {
+		if rowMatchesFilters(r, scope) {
+			total.add(r.TotEmp)
+		}
+	}
+	return int(total.sum().Float64), nil
+}
+
+// AreaTotals returns the summed detailed-occupation employment per area title
+func (s *MemoryStore) AreaTotals(ctx context.Context, year int) (map[string]int, error) {
+	scope := Filters{Year: year}
+	sums := make(map[string]*nullAccumulator)
+	for _, r := range s.rows {
+		if !rowMatchesFilters(r, scope) {
+			continue
+		}
+		acc, ok := sums[r.AreaTitle]
+		if !ok {
+			acc = &nullAccumulator{}
+			sums[r.AreaTitle] = acc
+		}
+		acc.add(r.TotEmp)
+	}
+	totals := make(map[string]int, len(sums))
+	for title, acc := range sums {
+		totals[title] = int(acc.sum().Float64)
+	}
+	return totals, nil
+}
+
+// OccupationGroupTitles returns the SOC group titles loaded with the store.
+// CSV files carry detailed occupations only, so this is usually empty and
+// callers fall back to the built-in major group titles.
+func (s *MemoryStore) OccupationGroupTitles(ctx context.Context) (map[string]string, error) {
+	return s.groups, nil
+}
+
+// PriceParities returns the regional price parities loaded with the store
+func (s *MemoryStore) PriceParities(ctx context.Context) (map[string]float64, error) {
+	return s.parities, nil
+}
+
+// CPIIndex returns the CPI index loaded with the store
+func (s *MemoryStore) CPIIndex(ctx context.Context) (map[int]float64, error) {
+	return s.cpi, nil
+}
+
+// ListYears returns the distinct data years, newest first
+func (s *MemoryStore) ListYears(ctx context.Context) ([]int, error) {
+	seen := make(map[int]bool)
+	var years []int
+	for _, r := range s.rows {
+		if !seen[r.Year] {
+			seen[r.Year] = true
+			years = append(years, r.Year)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+	return years, nil
+}
+
+// distinct collects the sorted unique values selected by pick
+func (s *MemoryStore) distinct(pick func(careerRow) (string, bool)) []string {
+	seen := make(map[string]struct{})
+	var values []string
+	for _, r := range s.rows {
+		v, ok := pick(r)
+		if !ok {
+			continue
+		}
+		if _, dup := seen[v]; dup {
+			continue
+		}
+		seen[v] = struct{}{}
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// rowMatchesFilters is the in-memory equivalent of the WHERE clause built by buildQuery
+func rowMatchesFilters(r careerRow, filters Filters) bool {
+	if filters.Year > 0 && r.Year != filters.Year {
+		return false
+	}
+	if r.OccCode == "00-0000" {
+		return false
+	}
+	if filters.Location != "" && !textMatches(filters.locationMatchMode(), r.AreaTitle, filters.Location) {
+		return false
+	}
+	if filters.AreaCode != "" && r.AreaCode != filters.AreaCode {
+		return false
+	}
+	if len(filters.Areas) > 0 && !containsString(filters.Areas, r.AreaTitle) {
+		return false
+	}
+	if filters.Occupation != "" && (r.OccTitle == "" || !textMatches(filters.OccupationMatch, r.OccTitle, filters.Occupation)) {
+		return false
+	}
+	if filters.OccCode != "" && r.OccCode != filters.OccCode {
+		return false
+	}
+	if filters.OccGroup != "" {
+		if prefix, _, ok := socGroupPrefix(filters.OccGroup); !ok || !strings.HasPrefix(r.OccCode, prefix) {
+			return false
+		}
+	}
+
+	if filters.Education != "" && filters.Education != "Any" {
+		allowedEdu := getAllowedEducationValues(filters.Education)
+		if len(allowedEdu) == 1 && allowedEdu[0] == "__EXACT__POSTSECONDARY_NONDEGREE__" {
+			if r.Education != "Postsecondary nondegree award" {
+				return false
+			}
+		} else if len(allowedEdu) > 0 && !containsString(allowedEdu, r.Education) {
+			return false
+		}
+	}
+
+	if filters.Experience != "" && filters.Experience != "Any" {
+		allowedExp := getAllowedExperienceValues(filters.Experience)
+		if len(allowedExp) > 0 {
+			includesNone := false
+			for _, v := range allowedExp {
+				if strings.EqualFold(v, "None") {
+					includesNone = true
+					break
+				}
+			}
+			if !(includesNone && r.Experience == "") && !containsString(allowedExp, r.Experience) {
+				return false
+			}
+		}
+	}
+
+	if filters.MinSalary > 0 && !rowMeetsMinSalary(r, float64(filters.MinSalary)) {
+		return false
+	}
+	return true
+}
+
+// containsFold is a case-insensitive substring test (ILIKE '%sub%')
+func containsFold(s, sub string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(sub))
+}
+
+// textMatches is the Go equivalent of textMatchClause for the given match mode
+func textMatches(mode, s, value string) bool {
+	switch mode {
+	case matchExact:
+		return strings.EqualFold(s, value)
+	case matchPrefix:
+		return strings.HasPrefix(strings.ToLower(s), strings.ToLower(value))
+	default:
+		return containsFold(s, value)
+	}
+}
+
+// containsString reports whether v is present in values (empty v never matches, like NULL)
+func containsString(values []string, v string) bool {
+	if v == "" {
+		return false
+	}
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
+
+// isNationalAreaTitle reports whether an area title is one of the U.S.-wide labels
+func isNationalAreaTitle(title string) bool {
+	switch title {
+	case "U.S.", "United States", "USA", "US":
+		return true
+	}
+	return false
+}
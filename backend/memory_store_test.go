@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testCareerCSV = `AREA_TITLE,OCC_CODE,OCC_TITLE,Education,Experience,TOT_EMP,A_MEDIAN,A_PCT10,A_PCT25,A_PCT75,A_PCT90
+U.S.,00-0000,All Occupations,,,151853870,48060,29050,35160,80450,128960
+U.S.,15-1252,Software Developers,Bachelor's degree,,1654440,132270,77020,101200,167540,208620
+Michigan,00-0000,All Occupations,,,4300000,47000,28000,34000,75000,115000
+Michigan,15-1252,Software Developers,Bachelor's degree,,40000,105000,70000,88000,130000,160000
+Michigan,29-1141,Registered Nurses,Bachelor's degree,,100000,86000,64000,75000,99000,110000
+Michigan,29-1151,Nurse Anesthetists,Master's degree,,2000,200000,150000,180000,,
+Michigan,11-1021,General and Operations Managers,Bachelor's degree,5 years or more,60000,100000,50000,70000,150000,#
+"Detroit-Warren-Dearborn, MI",29-1141,Registered Nurses,Bachelor's degree,,40000,84000,62000,72000,96000,108000
+"Toledo, OH",29-1141,Registered Nurses,Bachelor's degree,,8000,80000,60000,70000,90000,100000
+Michigan nonmetropolitan area,35-2014,Cooks Restaurant,No formal educational credential,Less than 5 years,3000,30000,25000,27000,34000,38000
+`
+
+func newTestMemoryStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	rows, err := readCareerCSV(strings.NewReader(testCareerCSV))
+	if err != nil {
+		t.Fatalf("readCareerCSV: %v", err)
+	}
+	return NewMemoryStore(rows)
+}
+
+// testAreaCareerCSV holds Registered Nurses in overlapping national, state,
+// metro and nonmetro areas with OEWS codes and types. The nation is labelled
+// twice; NationalTotal reads the "U.S." row.
+const testAreaCareerCSV = `AREA,AREA_TITLE,AREA_TYPE,OCC_CODE,OCC_TITLE,TOT_EMP,A_MEDIAN
+99,U.S.,1,00-0000,All Occupations,151853870,48060
+99,U.S.,1,29-1141,Registered Nurses,3175390,86070
+99,United States,1,00-0000,All Occupations,151000000,48060
+99,United States,1,29-1141,Registered Nurses,3175390,86070
+26,Michigan,2,29-1141,Registered Nurses,100000,86000
+19820,"Detroit-Warren-Dearborn, MI",4,29-1141,Registered Nurses,40000,84000
+19820,"Detroit-Warren-Dearborn, MI",4,41-2031,Retail Salespersons,60000,32000
+11460,"Ann Arbor, MI",4,29-1141,Registered Nurses,5000,85000
+45780,"Toledo, OH",4,29-1141,Registered Nurses,8000,80000
+2600004,Upper Peninsula of Michigan nonmetropolitan area,6,29-1141,Registered Nurses,3000,78000
+`
+
+// newTestAreaHandlers returns Handlers over a MemoryStore of testAreaCareerCSV
+func newTestAreaHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	rows, err := readCareerCSV(strings.NewReader(testAreaCareerCSV))
+	if err != nil {
+		t.Fatalf("readCareerCSV: %v", err)
+	}
+	return NewHandlers(NewMemoryStore(rows))
+}
+
+// calculate runs /api/calculate?query against h and decodes the result,
+// failing the test unless the handler answers 200
+func calculate(t *testing.T, h *Handlers, query string) CalculationResult {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?"+query, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("%s: expected 200, got %d: %s", query, rr.Code, rr.Body)
+	}
+	var result CalculationResult
+	if err := json.NewDecoder(rr.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// aggregateFor runs the calculation aggregation for filters against a store
+func aggregateFor(t *testing.T, store CareerDataStore, filters Filters) JobAggregate {
+	t.Helper()
+	rows, err := store.MatchingRows(context.Background(), filters)
+	if err != nil {
+		t.Fatalf("MatchingRows(%+v): %v", filters, err)
+	}
+	return aggregateRows(rows, filters)
+}
+
+func TestReadCareerCSVHandlesOEWSSymbols(t *testing.T) {
+	store := newTestMemoryStore(t)
+	for _, r := range store.rows {
+		if r.OccCode == "11-1021" {
+			if r.Pct90.Float64 != topCodeAnnual || r.TopCoded != 1<<4 {
+				t.Errorf("expected '#' to be the top-code cap, got %+v", r)
+			}
+			if r.Experience != "5 years or more" {
+				t.Errorf("unexpected experience %q", r.Experience)
+			}
+			return
+		}
+	}
+	t.Fatal("row 11-1021 not loaded")
+}
+
+func TestMemoryStoreAggregateMatchesBuildQuerySemantics(t *testing.T) {
+	store := newTestMemoryStore(t)
+
+	// ILIKE '%nurse%' matches both Registered Nurses and Nurse Anesthetists
+	agg := aggregateFor(t, store, Filters{Location: "Michigan", Occupation: "nurse", SalaryMethod: salaryMethodAverage})
+	if agg.MatchingJobs.Float64 != 102000 {
+		t.Errorf("expected 102000 matching jobs, got %v", agg.MatchingJobs.Float64)
+	}
+	if agg.Median.Float64 != 143000 {
+		t.Errorf("expected AVG median 143000, got %v", agg.Median.Float64)
+	}
+	// AVG ignores NULL percentiles
+	if agg.Pct90.Float64 != 110000 {
+		t.Errorf("expected AVG pct90 110000, got %v", agg.Pct90.Float64)
+	}
+	// The default weights by employment: (86000*100000 + 200000*2000) / 102000
+	agg = aggregateFor(t, store, Filters{Location: "Michigan", Occupation: "nurse"})
+	if agg.SalaryMethod != salaryMethodWeighted || int(agg.Median.Float64) != 88235 {
+		t.Errorf("expected weighted median 88235, got %v (%s)", agg.Median.Float64, agg.SalaryMethod)
+	}
+
+	// Education ladder: Bachelor's includes lower levels but not Master's
+	agg = aggregateFor(t, store, Filters{Location: "Michigan", Occupation: "nurse", Education: "Bachelor's degree"})
+	if agg.MatchingJobs.Float64 != 100000 {
+		t.Errorf("expected 100000 with education ladder, got %v", agg.MatchingJobs.Float64)
+	}
+
+	// Experience "None" includes NULL experience rows
+	agg = aggregateFor(t, store, Filters{Location: "Michigan", Occupation: "Managers", Experience: "None"})
+	if agg.MatchingJobs.Valid {
+		t.Errorf("expected no rows for managers requiring experience, got %v", agg.MatchingJobs.Float64)
+	}
+
+	// Salary filter is inclusive across median/pct75/pct90
+	agg = aggregateFor(t, store, Filters{Location: "Michigan", Occupation: "Software", MinSalary: 150000})
+	if agg.MatchingJobs.Float64 != 40000 {
+		t.Errorf("expected pct90 to satisfy the salary filter, got %v", agg.MatchingJobs.Float64)
+	}
+}
+
+func TestMemoryStoreOccupationMatchModes(t *testing.T) {
+	store := newTestMemoryStore(t)
+
+	cases := []struct {
+		filters Filters
+		want    float64
+	}{
+		// contains: "Nurse" also picks up Nurse Anesthetists
+		{Filters{Location: "Michigan", Occupation: "Nurse"}, 102000},
+		{Filters{Location: "Michigan", Occupation: "registered nurses", OccupationMatch: matchExact}, 100000},
+		{Filters{Location: "Michigan", Occupation: "Nurse", OccupationMatch: matchExact}, 0},
+		{Filters{Location: "Michigan", Occupation: "Nurse", OccupationMatch: matchPrefix}, 2000},
+		{Filters{Location: "Michigan", OccCode: "29-1141"}, 100000},
+		// SOC groups: major 29-0000 covers both nursing occupations, broad 29-1140 only RNs
+		{Filters{Location: "Michigan", OccGroup: "29-0000"}, 102000},
+		{Filters{Location: "Michigan", OccGroup: "29-1140"}, 100000},
+		{Filters{Location: "Michigan", OccGroup: "15-1200"}, 40000},
+	}
+	for _, c := range cases {
+		if got := aggregateFor(t, store, c.filters).MatchingJobs.Float64; got != c.want {
+			t.Errorf("%+v: expected %v, got %v", c.filters, c.want, got)
+		}
+	}
+
+	occupations, _ := store.ListOccupations(context.Background())
+	if len(occupations) == 0 || occupations[0] != (Occupation{Code: "35-2014", Title: "Cooks Restaurant"}) {
+		t.Errorf("unexpected occupations %v", occupations)
+	}
+}
+
+func TestMemoryStoreLookups(t *testing.T) {
+	store := newTestMemoryStore(t)
+	ctx := context.Background()
+
+	states, _ := store.ListStates(ctx)
+	if len(states) != 1 || states[0] != "Michigan" {
+		t.Errorf("unexpected states %v", states)
+	}
+
+	areas, _ := store.AreasForState(ctx, "Michigan")
+	want := []string{"Detroit-Warren-Dearborn, MI", "Michigan", "Michigan nonmetropolitan area"}
+	if strings.Join(areas, "|") != strings.Join(want, "|") {
+		t.Errorf("unexpected areas %v", areas)
+	}
+
+	national, err := store.NationalTotal(ctx, 2023)
+	if err != nil || national != 151853870 {
+		t.Errorf("unexpected national total %d (%v)", national, err)
+	}
+}
+
+func TestReadCareerCSVDataYear(t *testing.T) {
+	csv := `DATA_YEAR,AREA_TITLE,OCC_CODE,OCC_TITLE,TOT_EMP,A_MEDIAN
+2022,Michigan,29-1141,Registered Nurses,95000,80000
+2023,Michigan,29-1141,Registered Nurses,100000,86000
+`
+	rows, err := readCareerCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryStore(rows)
+	ctx := context.Background()
+
+	years, _ := store.ListYears(ctx)
+	if len(years) != 2 || years[0] != 2023 || years[1] != 2022 {
+		t.Errorf("expected [2023 2022], got %v", years)
+	}
+	agg := aggregateFor(t, store, Filters{Location: "Michigan", Year: 2022})
+	if agg.MatchingJobs.Float64 != 95000 {
+		t.Errorf("expected only 2022 rows, got %v", agg.MatchingJobs.Float64)
+	}
+
+	// Files without DATA_YEAR belong to the default year
+	legacy := newTestMemoryStore(t)
+	if years, _ := legacy.ListYears(ctx); len(years) != 1 || years[0] != defaultDataYear {
+		t.Errorf("expected [%d], got %v", defaultDataYear, years)
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migration is a versioned schema change. Statements are rendered per dialect
+// so the same history applies to Postgres and SQLite.
+type migration struct {
+	version     int
+	description string
+	statements  func(d dialect) []string
+	// backfill optionally fills the new schema from existing data, in the
+	// migration's transaction after its statements
+	backfill func(ctx context.Context, tx *sql.Tx, d dialect) error
+}
+
+// migrations lists every schema change in order. Never edit an applied
+// migration; append a new one instead.
+var migrations = []migration{
+	{
+		version:     1,
+		description: "create career_data",
+		statements: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS career_data (
+					id ` + d.autoIncrementPK + `,
+					area_title VARCHAR(255) NOT NULL,
+					occ_code VARCHAR(15) NOT NULL,
+					occ_title VARCHAR(255),
+					education VARCHAR(255),
+					experience VARCHAR(255),
+					tot_emp INTEGER,
+					a_median INTEGER,
+					a_pct10 INTEGER,
+					a_pct25 INTEGER,
+					a_pct75 INTEGER,
+					a_pct90 INTEGER,
+					UNIQUE (area_title, occ_code)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_career_data_occ_title ON career_data (occ_title)`,
+				`CREATE INDEX IF NOT EXISTS idx_career_data_area_title ON career_data (area_title)`,
+				`CREATE INDEX IF NOT EXISTS idx_career_data_education ON career_data (education)`,
+				`CREATE INDEX IF NOT EXISTS idx_career_data_experience ON career_data (experience)`,
+			}
+		},
+	},
+	{
+		version:     2,
+		description: "add data_year to career_data",
+		statements: func(d dialect) []string {
+			if d.name == sqliteDialect.name {
+				// SQLite cannot drop a table constraint, so rebuild the table
+				return []string{
+					`CREATE TABLE career_data_new (
+						id ` + d.autoIncrementPK + `,
+						data_year INTEGER NOT NULL DEFAULT 2023,
+						area_title VARCHAR(255) NOT NULL,
+						occ_code VARCHAR(15) NOT NULL,
+						occ_title VARCHAR(255),
+						education VARCHAR(255),
+						experience VARCHAR(255),
+						tot_emp INTEGER,
+						a_median INTEGER,
+						a_pct10 INTEGER,
+						a_pct25 INTEGER,
+						a_pct75 INTEGER,
+						a_pct90 INTEGER,
+						UNIQUE (data_year, area_title, occ_code)
+					)`,
+					`INSERT INTO career_data_new
+						(id, area_title, occ_code, occ_title, education, experience, tot_emp, a_median, a_pct10, a_pct25, a_pct75, a_pct90)
+						SELECT id, area_title, occ_code, occ_title, education, experience, tot_emp, a_median, a_pct10, a_pct25, a_pct75, a_pct90
+						FROM career_data`,
+					`DROP TABLE career_data`,
+					`ALTER TABLE career_data_new RENAME TO career_data`,
+					`CREATE INDEX IF NOT EXISTS idx_career_data_occ_title ON career_data (occ_title)`,
+					`CREATE INDEX IF NOT EXISTS idx_career_data_area_title ON career_data (area_title)`,
+					`CREATE INDEX IF NOT EXISTS idx_career_data_education ON career_data (education)`,
+					`CREATE INDEX IF NOT EXISTS idx_career_data_experience ON career_data (experience)`,
+					`CREATE INDEX IF NOT EXISTS idx_career_data_data_year ON career_data (data_year)`,
+				}
+			}
+			return []string{
+				`ALTER TABLE career_data ADD COLUMN IF NOT EXISTS data_year INTEGER NOT NULL DEFAULT 2023`,
+				`ALTER TABLE career_data DROP CONSTRAINT IF EXISTS career_data_area_title_occ_code_key`,
+				`CREATE UNIQUE INDEX IF NOT EXISTS career_data_year_area_occ_key ON career_data (data_year, area_title, occ_code)`,
+				`CREATE INDEX IF NOT EXISTS idx_career_data_data_year ON career_data (data_year)`,
+			}
+		},
+	},
+	{
+		version:     3,
+		description: "create career_data_history",
+		statements: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS career_data_history (
+					id ` + d.autoIncrementPK + `,
+					data_year INTEGER NOT NULL,
+					area_title VARCHAR(255) NOT NULL,
+					occ_code VARCHAR(15) NOT NULL,
+					occ_title VARCHAR(255),
+					education VARCHAR(255),
+					experience VARCHAR(255),
+					tot_emp INTEGER,
+					a_median INTEGER,
+					a_pct10 INTEGER,
+					a_pct25 INTEGER,
+					a_pct75 INTEGER,
+					a_pct90 INTEGER,
+					UNIQUE (data_year, area_title, occ_code)
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_career_data_history_area_occ ON career_data_history (area_title, occ_title)`,
+			}
+		},
+	},
+	{
+		version:     4,
+		description: "add area_code to career_data and career_data_history",
+		statements: func(d dialect) []string {
+			// SQLite has no ADD COLUMN IF NOT EXISTS; the version check guards re-runs
+			ifNotExists := "IF NOT EXISTS "
+			if d.name == sqliteDialect.name {
+				ifNotExists = ""
+			}
+			return []string{
+				`ALTER TABLE career_data ADD COLUMN ` + ifNotExists + `area_code VARCHAR(10)`,
+				`ALTER TABLE career_data_history ADD COLUMN ` + ifNotExists + `area_code VARCHAR(10)`,
+				`CREATE INDEX IF NOT EXISTS idx_career_data_area_code ON career_data (area_code)`,
+			}
+		},
+	},
+	{
+		version:     5,
+		description: "create occupation_groups",
+		statements: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS occupation_groups (
+					code VARCHAR(15) PRIMARY KEY,
+					level VARCHAR(10) NOT NULL,
+					title VARCHAR(255) NOT NULL
+				)`,
+			}
+		},
+	},
+	{
+		version:     6,
+		description: "create regional_price_parities",
+		statements: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS regional_price_parities (
+					area_title VARCHAR(255) PRIMARY KEY,
+					rpp DOUBLE PRECISION NOT NULL
+				)`,
+			}
+		},
+	},
+	{
+		version:     7,
+		description: "create cpi_index",
+		statements: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS cpi_index (
+					year INTEGER PRIMARY KEY,
+					cpi DOUBLE PRECISION NOT NULL
+				)`,
+			}
+		},
+	},
+	{
+		version:     8,
+		description: "add hourly wage percentiles to career_data and career_data_history",
+		statements: func(d dialect) []string {
+			ifNotExists := "IF NOT EXISTS "
+			if d.name == sqliteDialect.name {
+				ifNotExists = ""
+			}
+			var stmts []string
+			for _, table := range []string{careerDataTable, careerHistoryTable} {
+				for _, col := range []string{"h_median", "h_pct10", "h_pct25", "h_pct75", "h_pct90"} {
+					stmts = append(stmts, `ALTER TABLE `+table+` ADD COLUMN `+ifNotExists+col+` DOUBLE PRECISION`)
+				}
+			}
+			return stmts
+		},
+	},
+	{
+		version:     9,
+		description: "add top-coded wage flags to career_data and career_data_history",
+		statements: func(d dialect) []string {
+			ifNotExists := "IF NOT EXISTS "
+			if d.name == sqliteDialect.name {
+				ifNotExists = ""
+			}
+			var stmts []string
+			for _, table := range []string{careerDataTable, careerHistoryTable} {
+				for _, col := range []string{"a_top_coded", "h_top_coded"} {
+					stmts = append(stmts, `ALTER TABLE `+table+` ADD COLUMN `+ifNotExists+col+` INTEGER NOT NULL DEFAULT 0`)
+				}
+			}
+			return stmts
+		},
+	},
+	{
+		version:     10,
+		description: "create areas",
+		statements: func(d dialect) []string {
+			return []string{
+				`CREATE TABLE IF NOT EXISTS areas (
+					area_title VARCHAR(255) PRIMARY KEY,
+					area_code VARCHAR(10),
+					area_type VARCHAR(20) NOT NULL,
+					states VARCHAR(255) NOT NULL DEFAULT ''
+				)`,
+				`CREATE INDEX IF NOT EXISTS idx_areas_area_code ON areas (area_code)`,
+			}
+		},
+		backfill: backfillAreas,
+	},
+}
+
+// migrate applies all pending migrations, each in its own transaction,
+// recording applied versions in schema_migrations
+func migrate(ctx context.Context, db *sql.DB, d dialect) error {
+	if _, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		description VARCHAR(255) NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := applyMigration(ctx, db, d, m); err != nil {
+			return fmt.Errorf("migration %d (%s): %v", m.version, m.description, err)
+		}
+		log.Printf("Applied migration %d: %s", m.version, m.description)
+	}
+	return nil
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]bool, error) {
+	applied := make(map[int]bool)
+	rows, err := db.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error reading schema_migrations: %v", err)
+	}
+	return applied, nil
+}
+
+// checkSchema fails when a migration has not been applied, so a server
+// started against an outdated database exits at startup instead of failing
+// every query
+func checkSchema(ctx context.Context, db *sql.DB) error {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return fmt.Errorf("database schema is not migrated (%v); start with DB_MIGRATE=true or run ingest", err)
+	}
+	for _, m := range migrations {
+		if !applied[m.version] {
+			return fmt.Errorf("database schema is missing migration %d (%s); start with DB_MIGRATE=true or run ingest", m.version, m.description)
+		}
+	}
+	return nil
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, d dialect, m migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range m.statements(d) {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if m.backfill != nil {
+		if err := m.backfill(ctx, tx, d); err != nil {
+			return err
+		}
+	}
+	insert := fmt.Sprintf("INSERT INTO schema_migrations (version, description) VALUES (%s, %s)",
+		d.placeholder(1), d.placeholder(2))
+	if _, err := tx.ExecContext(ctx, insert, m.version, m.description); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
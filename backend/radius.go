@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxRadiusMiles bounds radiusMiles so a search stays a commute, not a region
+const maxRadiusMiles = 250
+
+// earthRadiusMiles is the mean radius of the Earth used for great-circle distances
+const earthRadiusMiles = 3958.8
+
+// latLng is a point in decimal degrees
+type latLng struct {
+	Lat float64
+	Lng float64
+}
+
+// AreaCentroids holds the centroid of each area, keyed by OEWS area title or
+// area code
+type AreaCentroids struct {
+	byTitle map[string]latLng
+	byCode  map[string]latLng
+}
+
+// RadiusSearch describes a radiusMiles calculation: its origin and the areas
+// whose centroid lies within the radius, nearest first
+type RadiusSearch struct {
+	Lat   float64        `json:"lat"`
+	Lng   float64        `json:"lng"`
+	Near  string         `json:"near,omitempty"`
+	Miles float64        `json:"radiusMiles"`
+	Areas []IncludedArea `json:"areas"`
+}
+
+// IncludedArea is an area of a radius search with the distance from the
+// origin to its centroid, rounded to a tenth of a mile
+type IncludedArea struct {
+	Area
+	DistanceMiles float64 `json:"distanceMiles"`
+}
+
+// readAreaCentroidsCSVFile opens and parses an area centroid CSV file
+func readAreaCentroidsCSVFile(path string) (*AreaCentroids, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening area centroid CSV: %v", err)
+	}
+	defer f.Close()
+
+	centroids, err := readAreaCentroidsCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return centroids, nil
+}
+
+// readAreaCentroidsCSV parses area centroids with LAT and LNG (or LON)
+// columns and an AREA_TITLE or AREA (OEWS area code) column identifying the
+// area, matched by header name, case-insensitive
+func readAreaCentroidsCSV(r io.Reader) (*AreaCentroids, error) {
+	table, err := newCSVTable(r)
+	if err != nil {
+		return nil, err
+	}
+	table.alias("LON", "LNG")
+	if err := table.require("LAT", "LNG"); err != nil {
+		return nil, err
+	}
+	if !table.has("AREA_TITLE") && !table.has("AREA") {
+		return nil, fmt.Errorf("missing required column AREA_TITLE or AREA")
+	}
+
+	centroids := &AreaCentroids{byTitle: make(map[string]latLng), byCode: make(map[string]latLng)}
+	for {
+		rec, err := table.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		text := rec.text
+		title, code := text("AREA_TITLE"), text("AREA")
+		if title == "" && code == "" {
+			continue
+		}
+		lat, latErr := strconv.ParseFloat(text("LAT"), 64)
+		lng, lngErr := strconv.ParseFloat(text("LNG"), 64)
+		if latErr != nil || lngErr != nil || !validLatLng(lat, lng) {
+			return nil, fmt.Errorf("line %d: invalid coordinates %q, %q", rec.line, text("LAT"), text("LNG"))
+		}
+		point := latLng{Lat: lat, Lng: lng}
+		if title != "" {
+			centroids.byTitle[title] = point
+		}
+		if code != "" {
+			centroids.byCode[code] = point
+		}
+	}
+	return centroids, nil
+}
+
+// validLatLng reports whether lat and lng are decimal degrees on the globe
+func validLatLng(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// of returns the centroid of an area, by title first and then by code
+func (c *AreaCentroids) of(a Area) (latLng, bool) {
+	if p, ok := c.byTitle[a.Title]; ok {
+		return p, true
+	}
+	if a.Code != "" {
+		if p, ok := c.byCode[a.Code]; ok {
+			return p, true
+		}
+	}
+	return latLng{}, false
+}
+
+// distanceMiles returns the great-circle (haversine) distance between two points
+func distanceMiles(a, b latLng) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat, dLng := rad(b.Lat-a.Lat), rad(b.Lng-a.Lng)
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(rad(a.Lat))*math.Cos(rad(b.Lat))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// areasWithin returns the metro and nonmetropolitan areas holding career data
+// whose centroid lies within miles of origin, nearest first. States and the
+// nation are left out: their rows overlap the metro and nonmetro areas inside
+// them and would count the same jobs twice.
+func (h *Handlers) areasWithin(ctx context.Context, origin latLng, miles float64) ([]IncludedArea, error) {
+	metadata, err := h.store.Areas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	titles, err := h.store.ListAreas(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var included []IncludedArea
+	for _, title := range titles {
+		a, ok := metadata[title]
+		if !ok {
+			a = Area{Title: title, Type: classifyAreaTitle(title)}
+		}
+		if a.Type != areaTypeMetro && a.Type != areaTypeNonmetro {
+			continue
+		}
+		centroid, ok := h.centroids.of(a)
+		if !ok {
+			continue
+		}
+		if d := distanceMiles(origin, centroid); d <= miles {
+			included = append(included, IncludedArea{Area: a, DistanceMiles: math.Round(d*10) / 10})
+		}
+	}
+	sort.Slice(included, func(i, j int) bool {
+		if included[i].DistanceMiles != included[j].DistanceMiles {
+			return included[i].DistanceMiles < included[j].DistanceMiles
+		}
+		return included[i].Title < included[j].Title
+	})
+	return included, nil
+}
+
+// parseRadiusFilters reads the /api/calculate filters of a radius search:
+// radiusMiles around lat/lng or around the centroid of the near area. The
+// included areas replace location, so location, areaCode and zip are rejected.
+func (h *Handlers) parseRadiusFilters(r *http.Request) (Filters, *RadiusSearch, error) {
+	filters, err := parseCriteria(r)
+	if err != nil {
+		return filters, nil, err
+	}
+	q := r.URL.Query()
+	if filters.Location != "" || filters.AreaCode != "" || strings.TrimSpace(q.Get("zip")) != "" {
+		return filters, nil, requestError("radiusMiles cannot be combined with location, areaCode or zip")
+	}
+	if h.centroids == nil {
+		return filters, nil, requestError("radiusMiles is not supported: no area centroids are loaded")
+	}
+	miles, err := strconv.ParseFloat(q.Get("radiusMiles"), 64)
+	if err != nil || !(miles > 0 && miles <= maxRadiusMiles) {
+		return filters, nil, requestError(fmt.Sprintf("radiusMiles must be a number greater than 0 and at most %d", maxRadiusMiles))
+	}
+
+	search := &RadiusSearch{Miles: miles}
+	near := strings.TrimSpace(q.Get("near"))
+	lat, lng := q.Get("lat"), q.Get("lng")
+	switch {
+	case near != "" && (lat != "" || lng != ""):
+		return filters, nil, requestError("near cannot be combined with lat and lng")
+	case near != "":
+		metadata, err := h.store.Areas(r.Context())
+		if err != nil {
+			return filters, nil, err
+		}
+		var origin latLng
+		found := false
+		for _, a := range metadata {
+			if strings.EqualFold(a.Title, near) {
+				origin, found = h.centroids.of(a)
+				search.Near = a.Title
+				break
+			}
+		}
+		if !found {
+			return filters, nil, requestError("no centroid found for area " + near)
+		}
+		search.Lat, search.Lng = origin.Lat, origin.Lng
+	case lat != "" && lng != "":
+		var latErr, lngErr error
+		search.Lat, latErr = strconv.ParseFloat(lat, 64)
+		search.Lng, lngErr = strconv.ParseFloat(lng, 64)
+		if latErr != nil || lngErr != nil || !validLatLng(search.Lat, search.Lng) {
+			return filters, nil, requestError("lat must be between -90 and 90 and lng between -180 and 180")
+		}
+	default:
+		return filters, nil, requestError("radiusMiles requires lat and lng or near")
+	}
+
+	search.Areas, err = h.areasWithin(r.Context(), latLng{Lat: search.Lat, Lng: search.Lng}, miles)
+	if err != nil {
+		return filters, nil, err
+	}
+	if len(search.Areas) == 0 {
+		return filters, nil, requestError(fmt.Sprintf("no area with career data within %g miles", miles))
+	}
+	for _, a := range search.Areas {
+		filters.Areas = append(filters.Areas, a.Title)
+	}
+	return filters, search, nil
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Toledo is located by its area code only; Michigan has a centroid but must
+// never be included
+const testAreaCentroidsCSV = `AREA_TITLE,AREA,LAT,LON
+"Detroit-Warren-Dearborn, MI",19820,42.33,-83.05
+"Ann Arbor, MI",11460,42.28,-83.74
+,45780,41.65,-83.54
+Upper Peninsula of Michigan nonmetropolitan area,2600004,46.50,-87.40
+Michigan,26,44.30,-85.60
+`
+
+// newTestRadiusHandlers returns the shared area Handlers with the test centroids
+func newTestRadiusHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	h := newTestAreaHandlers(t)
+	var err error
+	if h.centroids, err = readAreaCentroidsCSV(strings.NewReader(testAreaCentroidsCSV)); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestDistanceMiles(t *testing.T) {
+	// Detroit to Chicago is about 237 miles as the crow flies
+	if d := distanceMiles(latLng{42.33, -83.05}, latLng{41.88, -87.63}); math.Abs(d-237) > 2 {
+		t.Errorf("expected about 237 miles, got %.1f", d)
+	}
+	if d := distanceMiles(latLng{42.33, -83.05}, latLng{42.33, -83.05}); d != 0 {
+		t.Errorf("expected 0 miles, got %.1f", d)
+	}
+}
+
+func TestCalculateHandlerRadiusSearch(t *testing.T) {
+	h := newTestRadiusHandlers(t)
+	titles := func(result CalculationResult) string {
+		var list []string
+		for _, a := range result.Radius.Areas {
+			list = append(list, a.Title)
+		}
+		return strings.Join(list, "|")
+	}
+
+	result := calculate(t, h, "near=detroit-warren-dearborn,+mi&radiusMiles=60&occupation=Registered+Nurses")
+	if got := titles(result); got != "Detroit-Warren-Dearborn, MI|Ann Arbor, MI|Toledo, OH" {
+		t.Errorf("expected Detroit, Ann Arbor and Toledo nearest first, got %s", got)
+	}
+	if result.MatchingJobs != 53000 || result.TotalJobsRegion != 113000 {
+		t.Errorf("expected 53000 of 113000 jobs across the radius, got %d of %d", result.MatchingJobs, result.TotalJobsRegion)
+	}
+	if result.Location != "" || result.AreaCode != "" || result.Radius.Near != "Detroit-Warren-Dearborn, MI" {
+		t.Errorf("expected no single location, got %q %q near %q", result.Location, result.AreaCode, result.Radius.Near)
+	}
+
+	// A single included area is reported like a location
+	result = calculate(t, h, "lat=42.33&lng=-83.05&radiusMiles=10&occupation=Registered+Nurses")
+	if titles(result) != "Detroit-Warren-Dearborn, MI" || result.Location != "Detroit-Warren-Dearborn, MI" || result.AreaCode != "19820" {
+		t.Errorf("expected only the Detroit metro, got %+v", result)
+	}
+
+	// The state around the origin is never summed with its own metros
+	result = calculate(t, h, "near=Michigan&radiusMiles=250&occupation=Registered+Nurses")
+	if strings.Contains(titles(result), "Michigan|") || result.MatchingJobs != 56000 {
+		t.Errorf("expected the four metro and nonmetro areas only, got %s with %d jobs", titles(result), result.MatchingJobs)
+	}
+}
+
+func TestCalculateHandlerRejectsInvalidRadius(t *testing.T) {
+	h := newTestRadiusHandlers(t)
+	for _, query := range []string{
+		"radiusMiles=60",
+		"radiusMiles=0&lat=42.33&lng=-83.05",
+		"radiusMiles=300&lat=42.33&lng=-83.05",
+		"radiusMiles=NaN&lat=42.33&lng=-83.05",
+		"radiusMiles=60&lat=95&lng=-83.05",
+		"radiusMiles=60&lat=42.33",
+		"radiusMiles=60&near=Atlantis",
+		"radiusMiles=60&near=Michigan&lat=42.33&lng=-83.05",
+		"radiusMiles=60&near=Michigan&location=Michigan",
+		"radiusMiles=60&lat=0&lng=0",
+	} {
+		rr := httptest.NewRecorder()
+		h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+
+	h.centroids = nil
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?radiusMiles=60&lat=42.33&lng=-83.05", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without centroids, got %d", rr.Code)
+	}
+}
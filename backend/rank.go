@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Area types of the areas table (OEWS AREA_TYPE, or classifyAreaTitle for
+// data loaded without it)
+const (
+	areaTypeNational = "national"
+	areaTypeState    = "state"
+	areaTypeMetro    = "metro"
+	areaTypeNonmetro = "nonmetro"
+)
+
+// rankAreaTypes lists the areaType values accepted by /api/rank/areas
+var rankAreaTypes = []string{areaTypeState, areaTypeMetro, areaTypeNonmetro}
+
+// Sort keys accepted by /api/rank/areas
+const (
+	rankByMatchingJobs     = "matchingJobs"
+	rankByPercentageRegion = "percentageRegion"
+	rankByMedianSalary     = "medianSalary"
+)
+
+var rankAreaSorts = []string{rankByMatchingJobs, rankByPercentageRegion, rankByMedianSalary}
+
+// Sort keys accepted by /api/rank/occupations
+const rankByEstimatedMatchingJobs = "estimatedMatchingJobs"
+
+var rankOccupationSorts = []string{rankByMatchingJobs, rankByEstimatedMatchingJobs, rankByMedianSalary}
+
+// Ranking result sizes
+const (
+	defaultRankLimit = 10
+	maxRankLimit     = 100
+)
+
+// AreaRank is one area of the /api/rank/areas response
+type AreaRank struct {
+	Rank                      int        `json:"rank"`
+	Location                  string     `json:"location"`
+	AreaType                  string     `json:"areaType"`
+	MatchingJobs              int        `json:"matchingJobs"`
+	TotalJobsRegion           int        `json:"totalJobsRegion"`
+	PercentageRegion          float64    `json:"percentageRegion"`
+	EstimatedMatchingJobs     int        `json:"estimatedMatchingJobs"`
+	EstimatedPercentageRegion float64    `json:"estimatedPercentageRegion"`
+	SalaryInfo                SalaryInfo `json:"salaryInfo"`
+}
+
+// OccupationRank is one occupation of the /api/rank/occupations response
+type OccupationRank struct {
+	Rank                      int        `json:"rank"`
+	OccCode                   string     `json:"occCode"`
+	Occupation                string     `json:"occupation"`
+	Education                 string     `json:"education,omitempty"`
+	Experience                string     `json:"experience,omitempty"`
+	MatchingJobs              int        `json:"matchingJobs"`
+	PercentageRegion          float64    `json:"percentageRegion"`
+	EstimatedMatchingJobs     int        `json:"estimatedMatchingJobs"`
+	EstimatedPercentageRegion float64    `json:"estimatedPercentageRegion"`
+	SalaryInfo                SalaryInfo `json:"salaryInfo"`
+}
+
+// classifyAreaTitle derives the area type from an OEWS area title: U.S.
+// labels are national, "... nonmetropolitan area" titles are nonmetro,
+// titles with a state suffix after a comma ("Detroit-Warren-Dearborn, MI")
+// are metro areas and everything else is a state or territory
+func classifyAreaTitle(title string) string {
+	switch {
+	case isNationalAreaTitle(title):
+		return areaTypeNational
+	case strings.Contains(strings.ToLower(title), "nonmetropolitan area"):
+		return areaTypeNonmetro
+	case strings.Contains(title, ","):
+		return areaTypeMetro
+	default:
+		return areaTypeState
+	}
+}
+
+// RankAreasHandler handles /api/rank/areas: the calculation filters (without
+// a location) evaluated for every area, ranked by matching employment,
+// regional share or median wage. An occupation, code or SOC group is required
+// so a request never loads every row of the year.
+func (h *Handlers) RankAreasHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filters, err := parseCriteria(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !filters.hasOccupation() {
+		http.Error(w, "occupation, occCode or occGroup is required", http.StatusBadRequest)
+		return
+	}
+	sortBy := q.Get("sort")
+	if sortBy == "" {
+		sortBy = rankByMatchingJobs
+	}
+	if !containsString(rankAreaSorts, sortBy) {
+		http.Error(w, "sort must be one of: "+strings.Join(rankAreaSorts, ", "), http.StatusBadRequest)
+		return
+	}
+	areaType := q.Get("areaType")
+	if areaType != "" && !containsString(rankAreaTypes, areaType) {
+		http.Error(w, "areaType must be one of: "+strings.Join(rankAreaTypes, ", "), http.StatusBadRequest)
+		return
+	}
+	limit, err := parseRankLimit(q.Get("limit"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	year, err := h.resolveYear(r.Context(), q.Get("year"))
+	if err != nil {
+		writeError(w, err, "Error resolving data year")
+		return
+	}
+	filters.Year = year
+	filters.Location, filters.AreaCode = "", ""
+
+	rows, _, err := h.selectionRows(r.Context(), filters, h.store.MatchingRows)
+	if err != nil {
+		writeError(w, err, "Error ranking areas")
+		return
+	}
+	totals, err := h.store.AreaTotals(r.Context(), year)
+	if err != nil {
+		log.Printf("Error querying area totals: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	metadata, err := h.store.Areas(r.Context())
+	if err != nil {
+		log.Printf("Error querying areas: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	ranked := rankAreas(rows, totals, metadata, filters, areaType, sortBy)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"areas": ranked,
+		"sort":  sortBy,
+		"year":  year,
+		"count": len(ranked),
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// rankAreas groups matching rows by area, aggregates each area and orders
+// them by sortBy (ties by area title). National rows are never ranked and
+// areaType, when set, keeps only areas of that type according to the area metadata.
+func rankAreas(rows []careerRow, totals map[string]int, metadata map[string]Area, filters Filters, areaType, sortBy string) []AreaRank {
+	byArea := make(map[string][]careerRow)
+	for _, r := range rows {
+		t := areaTypeOf(metadata, r.AreaTitle)
+		if t == areaTypeNational || (areaType != "" && t != areaType) {
+			continue
+		}
+		byArea[r.AreaTitle] = append(byArea[r.AreaTitle], r)
+	}
+
+	type scored struct {
+		AreaRank
+		median float64
+	}
+	areas := make([]scored, 0, len(byArea))
+	for title, areaRows := range byArea {
+		agg := aggregateRows(areaRows, filters)
+		if !agg.MatchingJobs.Valid || agg.MatchingJobs.Float64 <= 0 {
+			continue
+		}
+		total := totals[title]
+		areas = append(areas, scored{
+			AreaRank: AreaRank{
+				Location:                  title,
+				AreaType:                  areaTypeOf(metadata, title),
+				MatchingJobs:              int(agg.MatchingJobs.Float64),
+				TotalJobsRegion:           total,
+				PercentageRegion:          percentOf(agg.MatchingJobs, total),
+				EstimatedMatchingJobs:     int(math.Round(agg.EstimatedMatchingJobs.Float64)),
+				EstimatedPercentageRegion: percentOf(agg.EstimatedMatchingJobs, total),
+				SalaryInfo:                salaryInfoFrom(agg),
+			},
+			median: agg.Median.Float64,
+		})
+	}
+
+	key := func(a scored) float64 {
+		switch sortBy {
+		case rankByPercentageRegion:
+			return a.PercentageRegion
+		case rankByMedianSalary:
+			return a.median
+		default:
+			return float64(a.MatchingJobs)
+		}
+	}
+	sort.Slice(areas, func(i, j int) bool {
+		if ki, kj := key(areas[i]), key(areas[j]); ki != kj {
+			return ki > kj
+		}
+		return areas[i].Location < areas[j].Location
+	})
+
+	ranked := make([]AreaRank, len(areas))
+	for i, a := range areas {
+		ranked[i] = a.AreaRank
+		ranked[i].Rank = i + 1
+	}
+	return ranked
+}
+
+// RankOccupationsHandler handles /api/rank/occupations: the occupations of
+// one location that satisfy the education, experience and salary filters,
+// grouped by occ_code and paged with limit and offset
+func (h *Handlers) RankOccupationsHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filters, err := parseFilters(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sortBy := q.Get("sort")
+	if sortBy == "" {
+		sortBy = rankByMatchingJobs
+	}
+	if !containsString(rankOccupationSorts, sortBy) {
+		http.Error(w, "sort must be one of: "+strings.Join(rankOccupationSorts, ", "), http.StatusBadRequest)
+		return
+	}
+	limit, err := parseRankLimit(q.Get("limit"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	offset, err := parseRankOffset(q.Get("offset"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	year, err := h.resolveYear(r.Context(), q.Get("year"))
+	if err != nil {
+		writeError(w, err, "Error resolving data year")
+		return
+	}
+	filters.Year = year
+
+	rows, _, err := h.selectionRows(r.Context(), filters, h.store.MatchingRows)
+	if err != nil {
+		writeError(w, err, "Error ranking occupations")
+		return
+	}
+	totalJobsRegion, err := h.store.RegionalTotal(r.Context(), filters)
+	if err != nil {
+		log.Printf("Error querying regional total jobs: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	location := filters.Location
+	if location == "" && len(rows) > 0 {
+		location = rows[0].AreaTitle
+	}
+
+	ranked := rankOccupations(rows, totalJobsRegion, filters, sortBy)
+	total := len(ranked)
+	// offset+limit may overflow for a huge offset, so bound the page first
+	start, end := min(offset, total), total
+	if offset < total && limit < total-offset {
+		end = offset + limit
+	}
+	page := ranked[start:end]
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"location":        location,
+		"occupations":     page,
+		"totalJobsRegion": totalJobsRegion,
+		"total":           total,
+		"limit":           limit,
+		"offset":          offset,
+		"sort":            sortBy,
+		"year":            year,
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// rankOccupations groups matching rows by occ_code, aggregates each
+// occupation and orders them by sortBy (ties by title). Occupations with no
+// matching employment are dropped.
+func rankOccupations(rows []careerRow, totalJobsRegion int, filters Filters, sortBy string) []OccupationRank {
+	byCode := make(map[string][]careerRow)
+	for _, r := range rows {
+		byCode[r.OccCode] = append(byCode[r.OccCode], r)
+	}
+
+	type scored struct {
+		OccupationRank
+		median float64
+	}
+	occupations := make([]scored, 0, len(byCode))
+	for code, occRows := range byCode {
+		agg := aggregateRows(occRows, filters)
+		if !agg.MatchingJobs.Valid || agg.MatchingJobs.Float64 <= 0 {
+			continue
+		}
+		first := occRows[0]
+		occupations = append(occupations, scored{
+			OccupationRank: OccupationRank{
+				OccCode:                   code,
+				Occupation:                first.OccTitle,
+				Education:                 first.Education,
+				Experience:                first.Experience,
+				MatchingJobs:              int(agg.MatchingJobs.Float64),
+				PercentageRegion:          percentOf(agg.MatchingJobs, totalJobsRegion),
+				EstimatedMatchingJobs:     int(math.Round(agg.EstimatedMatchingJobs.Float64)),
+				EstimatedPercentageRegion: percentOf(agg.EstimatedMatchingJobs, totalJobsRegion),
+				SalaryInfo:                salaryInfoFrom(agg),
+			},
+			median: agg.Median.Float64,
+		})
+	}
+
+	key := func(o scored) float64 {
+		switch sortBy {
+		case rankByEstimatedMatchingJobs:
+			return float64(o.EstimatedMatchingJobs)
+		case rankByMedianSalary:
+			return o.median
+		default:
+			return float64(o.MatchingJobs)
+		}
+	}
+	sort.Slice(occupations, func(i, j int) bool {
+		if ki, kj := key(occupations[i]), key(occupations[j]); ki != kj {
+			return ki > kj
+		}
+		if occupations[i].Occupation != occupations[j].Occupation {
+			return occupations[i].Occupation < occupations[j].Occupation
+		}
+		return occupations[i].OccCode < occupations[j].OccCode
+	})
+
+	ranked := make([]OccupationRank, len(occupations))
+	for i, o := range occupations {
+		ranked[i] = o.OccupationRank
+		ranked[i].Rank = i + 1
+	}
+	return ranked
+}
+
+// parseRankLimit validates the limit parameter of the ranking endpoints
+func parseRankLimit(raw string) (int, error) {
+	if raw == "" {
+		return defaultRankLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > maxRankLimit {
+		return 0, requestError(fmt.Sprintf("limit must be between 1 and %d", maxRankLimit))
+	}
+	return limit, nil
+}
+
+// parseRankOffset validates the offset parameter of the paged ranking endpoints
+func parseRankOffset(raw string) (int, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	offset, err := strconv.Atoi(raw)
+	if err != nil || offset < 0 {
+		return 0, requestError("offset must be a non-negative integer")
+	}
+	return offset, nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClassifyAreaTitle(t *testing.T) {
+	for title, want := range map[string]string{
+		"U.S.":                          areaTypeNational,
+		"Michigan":                      areaTypeState,
+		"Detroit-Warren-Dearborn, MI":   areaTypeMetro,
+		"Michigan nonmetropolitan area": areaTypeNonmetro,
+		"Northwest Lower Peninsula of Michigan nonmetropolitan area": areaTypeNonmetro,
+	} {
+		if got := classifyAreaTitle(title); got != want {
+			t.Errorf("classifyAreaTitle(%q) = %s, want %s", title, got, want)
+		}
+	}
+}
+
+func TestRankAreasHandler(t *testing.T) {
+	h := NewHandlers(newTestMemoryStore(t))
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"occupation=Registered+Nurses", []string{"Michigan", "Detroit-Warren-Dearborn, MI", "Toledo, OH"}},
+		// Both metros are 100% nurses; ties order by title
+		{"occupation=Registered+Nurses&sort=percentageRegion", []string{"Detroit-Warren-Dearborn, MI", "Toledo, OH", "Michigan"}},
+		{"occupation=Registered+Nurses&sort=medianSalary&limit=2", []string{"Michigan", "Detroit-Warren-Dearborn, MI"}},
+		{"occupation=Registered+Nurses&areaType=metro", []string{"Detroit-Warren-Dearborn, MI", "Toledo, OH"}},
+		{"occupation=Cooks&areaType=nonmetro", []string{"Michigan nonmetropolitan area"}},
+		// The national rows are never ranked
+		{"occupation=Software+Developers", []string{"Michigan"}},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		h.RankAreasHandler(rr, httptest.NewRequest("GET", "/api/rank/areas?"+tc.query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.query, rr.Code, rr.Body)
+		}
+		var body struct {
+			Areas []AreaRank `json:"areas"`
+			Count int        `json:"count"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Count != len(tc.want) {
+			t.Fatalf("%s: expected %d areas, got %+v", tc.query, len(tc.want), body.Areas)
+		}
+		for i, a := range body.Areas {
+			if a.Location != tc.want[i] || a.Rank != i+1 {
+				t.Errorf("%s: rank %d: expected %s, got %s (rank %d)", tc.query, i+1, tc.want[i], a.Location, a.Rank)
+			}
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	h.RankAreasHandler(rr, httptest.NewRequest("GET", "/api/rank/areas?occupation=Registered+Nurses&limit=1", nil))
+	var body struct {
+		Areas []AreaRank `json:"areas"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if m := body.Areas[0]; m.MatchingJobs != 100000 || m.TotalJobsRegion != 202000 || m.AreaType != areaTypeState {
+		t.Errorf("unexpected Michigan figures: %+v", m)
+	}
+}
+
+func TestRankAreasHandlerValidatesParameters(t *testing.T) {
+	h := NewHandlers(newTestMemoryStore(t))
+	for _, query := range []string{"sort=employment", "areaType=county", "limit=0", "limit=101", "limit=ten"} {
+		rr := httptest.NewRecorder()
+		h.RankAreasHandler(rr, httptest.NewRequest("GET", "/api/rank/areas?occupation=Nurse&"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+
+	// Without an occupation every row of the year would be ranked
+	rr := httptest.NewRecorder()
+	h.RankAreasHandler(rr, httptest.NewRequest("GET", "/api/rank/areas?education=Bachelor%27s+degree", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 without an occupation, got %d", rr.Code)
+	}
+}
+
+func TestRankOccupationsHandler(t *testing.T) {
+	h := NewHandlers(newTestMemoryStore(t))
+
+	cases := []struct {
+		query string
+		total int
+		want  []string
+	}{
+		{"location=Michigan", 4, []string{"29-1141", "11-1021", "15-1252", "29-1151"}},
+		// Registered Nurses peak at $110k, below the threshold
+		{"location=Michigan&minSalary=120000", 3, []string{"11-1021", "15-1252", "29-1151"}},
+		{"location=Michigan&minSalary=120000&sort=medianSalary", 3, []string{"29-1151", "15-1252", "11-1021"}},
+		{"location=Michigan&minSalary=120000&limit=2&offset=1", 3, []string{"15-1252", "29-1151"}},
+		// Nurse Anesthetists need a Master's, managers five years of experience
+		{"location=Michigan&education=Bachelor%27s+degree&experience=Less+than+5+years", 2, []string{"29-1141", "15-1252"}},
+		{"location=Michigan&offset=10", 4, nil},
+		{"location=Michigan&offset=9223372036854775800", 4, nil},
+	}
+	for _, tc := range cases {
+		rr := httptest.NewRecorder()
+		h.RankOccupationsHandler(rr, httptest.NewRequest("GET", "/api/rank/occupations?"+tc.query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tc.query, rr.Code, rr.Body)
+		}
+		var body struct {
+			Occupations     []OccupationRank `json:"occupations"`
+			Total           int              `json:"total"`
+			TotalJobsRegion int              `json:"totalJobsRegion"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Total != tc.total || len(body.Occupations) != len(tc.want) {
+			t.Fatalf("%s: expected %d of %d occupations, got %d: %+v", tc.query, len(tc.want), tc.total, body.Total, body.Occupations)
+		}
+		for i, o := range body.Occupations {
+			if o.OccCode != tc.want[i] {
+				t.Errorf("%s: position %d: expected %s, got %s", tc.query, i, tc.want[i], o.OccCode)
+			}
+		}
+		if body.TotalJobsRegion != 202000 {
+			t.Errorf("%s: unexpected regional total %d", tc.query, body.TotalJobsRegion)
+		}
+	}
+}
+
+func TestRankOccupationsHandlerValidatesParameters(t *testing.T) {
+	h := NewHandlers(newTestMemoryStore(t))
+	for _, query := range []string{"education=Bachelor%27s+degree", "location=Michigan&sort=title", "location=Michigan&offset=-1", "location=Michigan&limit=500"} {
+		rr := httptest.NewRecorder()
+		h.RankOccupationsHandler(rr, httptest.NewRequest("GET", "/api/rank/occupations?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// Wage adjustments selected with the adjust parameter
+const (
+	adjustNone = ""
+	// adjustRPP deflates wages by the BEA regional price parity of their area
+	// (100 = national average price level), so $90k in a 125 RPP metro
+	// reports as $72k
+	adjustRPP = "rpp"
+)
+
+var adjustModes = []string{adjustRPP}
+
+// readPriceParityCSVFile opens and parses a regional price parity CSV file
+func readPriceParityCSVFile(path string) (map[string]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening price parity CSV: %v", err)
+	}
+	defer f.Close()
+
+	parities, err := readPriceParityCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return parities, nil
+}
+
+// readPriceParityCSV parses a CSV with AREA_TITLE and RPP columns (matched by
+// header name, case-insensitive) into parities keyed by area title. Titles
+// must be spelled as in career_data. Rows with an empty RPP are skipped.
+func readPriceParityCSV(r io.Reader) (map[string]float64, error) {
+	table, err := newCSVTable(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := table.require("AREA_TITLE", "RPP"); err != nil {
+		return nil, err
+	}
+
+	parities := make(map[string]float64)
+	for {
+		rec, err := table.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		title, raw := rec.text("AREA_TITLE"), rec.text("RPP")
+		if title == "" || raw == "" {
+			continue
+		}
+		rpp, err := strconv.ParseFloat(raw, 64)
+		if err != nil || rpp <= 0 {
+			return nil, fmt.Errorf("line %d: invalid RPP %q", rec.line, raw)
+		}
+		parities[title] = rpp
+	}
+	return parities, nil
+}
+
+// applyPriceParities returns copies of rows with every wage divided by the
+// area's parity/100 and PriceParity set. Rows of areas without a parity
+// (including the national rows, which are 100 by definition) keep their
+// nominal wages.
+func applyPriceParities(rows []careerRow, parities map[string]float64) []careerRow {
+	adjusted := make([]careerRow, len(rows))
+	for i, r := range rows {
+		rpp, ok := parities[r.AreaTitle]
+		if ok && rpp > 0 {
+			r = scaleWages(r, 100/rpp)
+			r.PriceParity = sql.NullFloat64{Float64: rpp, Valid: true}
+		}
+		adjusted[i] = r
+	}
+	return adjusted
+}
+
+// appliedPriceParities lists the parity used for each adjusted area of rows
+func appliedPriceParities(rows []careerRow) map[string]float64 {
+	var applied map[string]float64
+	for _, r := range rows {
+		if !r.PriceParity.Valid {
+			continue
+		}
+		if applied == nil {
+			applied = make(map[string]float64)
+		}
+		applied[r.AreaTitle] = r.PriceParity.Float64
+	}
+	return applied
+}
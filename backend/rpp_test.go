@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPriceParityCSV(t *testing.T) {
+	parities, err := readPriceParityCSV(strings.NewReader("AREA,AREA_TITLE,RPP\n" +
+		"26,Michigan,93.2\n" +
+		"41940,\"San Jose-Sunnyvale-Santa Clara, CA\",125.9\n" +
+		"99,Unpublished,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{"Michigan": 93.2, "San Jose-Sunnyvale-Santa Clara, CA": 125.9}
+	if !reflect.DeepEqual(parities, want) {
+		t.Errorf("expected %v, got %v", want, parities)
+	}
+
+	for _, bad := range []string{"AREA_TITLE\nMichigan\n", "AREA_TITLE,RPP\nMichigan,0\n", "AREA_TITLE,RPP\nMichigan,n/a\n"} {
+		if _, err := readPriceParityCSV(strings.NewReader(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestApplyPriceParities(t *testing.T) {
+	rows := []careerRow{
+		{AreaTitle: "San Jose-Sunnyvale-Santa Clara, CA", Median: sql.NullFloat64{Float64: 125000, Valid: true}, Pct90: sql.NullFloat64{Float64: 250000, Valid: true}},
+		{AreaTitle: "U.S.", Median: sql.NullFloat64{Float64: 48060, Valid: true}},
+	}
+	adjusted := applyPriceParities(rows, map[string]float64{"San Jose-Sunnyvale-Santa Clara, CA": 125})
+	if adjusted[0].Median.Float64 != 100000 || adjusted[0].Pct90.Float64 != 200000 || adjusted[0].Pct10.Valid {
+		t.Errorf("unexpected adjusted wages %+v", adjusted[0])
+	}
+	if adjusted[1].Median.Float64 != 48060 || adjusted[1].PriceParity.Valid {
+		t.Errorf("expected areas without a parity to stay nominal, got %+v", adjusted[1])
+	}
+	if rows[0].Median.Float64 != 125000 {
+		t.Errorf("expected the input rows to be left untouched")
+	}
+}
+
+func TestCalculateHandlerAdjustsForPriceParity(t *testing.T) {
+	store := &fakeStore{
+		rows: []careerRow{{
+			AreaTitle: "Testville, MI",
+			OccTitle:  "Nurse",
+			TotEmp:    sql.NullFloat64{Float64: 500, Valid: true},
+			Median:    sql.NullFloat64{Float64: 90000, Valid: true},
+		}},
+		national: 100000,
+		regional: map[string]int{"Testville, MI": 10000},
+		years:    []int{2023},
+		parities: map[string]float64{"Testville, MI": 125},
+	}
+	h := NewHandlers(store)
+
+	nominal := calculate(t, h, "location=Testville,+MI&minSalary=80000")
+	if nominal.MatchingJobs != 500 || nominal.SalaryInfo.MedianSalary != 90000 || nominal.PriceParities != nil {
+		t.Errorf("unexpected nominal result %+v", nominal)
+	}
+	// $90k at a 125 parity is $72k in national-average dollars
+	adjusted := calculate(t, h, "location=Testville,+MI&minSalary=70000&adjust=rpp")
+	if adjusted.MatchingJobs != 500 || adjusted.SalaryInfo.MedianSalary != 72000 || !adjusted.MinSalaryMet {
+		t.Errorf("unexpected adjusted result %+v", adjusted)
+	}
+	if below := calculate(t, h, "location=Testville,+MI&minSalary=80000&adjust=rpp"); below.MatchingJobs != 0 || below.MinSalaryMet {
+		t.Errorf("expected the threshold to apply to adjusted wages, got %+v", below)
+	}
+	if adjusted.Adjust != adjustRPP || adjusted.PriceParities["Testville, MI"] != 125 {
+		t.Errorf("expected the applied parity to be reported, got %+v", adjusted)
+	}
+
+	rr := httptest.NewRecorder()
+	h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?location=Testville,+MI&adjust=cpi", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown adjust, got %d", rr.Code)
+	}
+}
+
+func TestUpsertPriceParities(t *testing.T) {
+	ctx := context.Background()
+	lite := newTestSQLiteStore(t)
+	if err := upsertPriceParities(ctx, lite.db, sqliteDialect, map[string]float64{"Michigan": 92, "Ohio": 91}); err != nil {
+		t.Fatal(err)
+	}
+	if err := upsertPriceParities(ctx, lite.db, sqliteDialect, map[string]float64{"Michigan": 93.2}); err != nil {
+		t.Fatal(err)
+	}
+	parities, err := lite.PriceParities(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"Michigan": 93.2, "Ohio": 91}; !reflect.DeepEqual(parities, want) {
+		t.Errorf("expected %v, got %v", want, parities)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Calculation scopes accepted by /api/calculate
+const (
+	// scopeArea selects areas by location, areaCode, zip or radiusMiles
+	scopeArea = "area"
+	// scopeState selects exactly one state's rows, named by location
+	scopeState = "state"
+	// scopeNational selects the national rows only
+	scopeNational = "national"
+)
+
+// parseCalculateFilters reads the /api/calculate filters for the requested
+// scope and data year. The area scope (the default) selects areas by location
+// or areaCode, by zip or by radiusMiles; a radius search is returned alongside
+// the filters.
+func (h *Handlers) parseCalculateFilters(r *http.Request, year int) (Filters, *RadiusSearch, error) {
+	q := r.URL.Query()
+	scope := q.Get("scope")
+	if scope == "" {
+		scope = scopeArea
+	}
+	zip := strings.TrimSpace(q.Get("zip"))
+	if scope != scopeArea && (zip != "" || q.Get("radiusMiles") != "") {
+		return Filters{}, nil, requestError("zip and radiusMiles require scope=area")
+	}
+
+	var filters Filters
+	var radius *RadiusSearch
+	var err error
+	switch {
+	case scope == scopeNational:
+		filters, err = h.parseNationalFilters(r, year)
+	case scope == scopeState:
+		filters, err = h.parseStateFilters(r)
+	case scope != scopeArea:
+		return Filters{}, nil, requestError("scope must be one of: national, state, area")
+	case q.Get("radiusMiles") != "":
+		filters, radius, err = h.parseRadiusFilters(r)
+	case zip != "":
+		filters, err = h.parseZIPFilters(r, zip)
+	default:
+		filters, err = parseFilters(r)
+	}
+	if err != nil {
+		return filters, nil, err
+	}
+	filters.Scope, filters.Year = scope, year
+	return filters, radius, nil
+}
+
+// parseNationalFilters reads the filters of a national calculation. It
+// selects the national area's rows rather than every area matching a
+// location, whose state, metro and nonmetro rows would count the same jobs
+// more than once. The national area is the one NationalTotal reads, so a
+// dataset labelling the nation several ways is still counted once.
+func (h *Handlers) parseNationalFilters(r *http.Request, year int) (Filters, error) {
+	filters, err := parseCriteria(r)
+	if err != nil {
+		return filters, err
+	}
+	if filters.Location != "" || filters.AreaCode != "" {
+		return filters, requestError("scope=national cannot be combined with location or areaCode")
+	}
+	national, err := h.store.NationalArea(r.Context(), year)
+	if err != nil {
+		return filters, err
+	}
+	if national == "" {
+		return filters, requestError("no national data is loaded")
+	}
+	filters.Areas = []string{national}
+	return filters, nil
+}
+
+// parseStateFilters reads the filters of a state calculation: location (a
+// state name or USPS abbreviation) or areaCode, but not both, must select a
+// state, whose row alone is matched so metros and nonmetro areas named after
+// the state are not added to it
+func (h *Handlers) parseStateFilters(r *http.Request) (Filters, error) {
+	filters, err := parseFilters(r)
+	if err != nil {
+		return filters, err
+	}
+	if filters.Location != "" && filters.AreaCode != "" {
+		return filters, requestError("scope=state takes location or areaCode, not both")
+	}
+	metadata, err := h.store.Areas(r.Context())
+	if err != nil {
+		return filters, err
+	}
+
+	var state Area
+	if filters.Location != "" {
+		if name, _, ok := lookupState(filters.Location); ok {
+			state = metadata[name]
+		}
+	} else {
+		for _, a := range metadata {
+			if a.Code == filters.AreaCode && a.Type == areaTypeState {
+				state = a
+				break
+			}
+		}
+	}
+	if state.Type != areaTypeState {
+		return filters, requestError("scope=state requires location to name a state with career data")
+	}
+	filters.Location, filters.LocationMatch = state.Title, matchExact
+	return filters, nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCalculateHandlerScopes(t *testing.T) {
+	h := newTestAreaHandlers(t)
+
+	// The national view counts the national row NationalTotal reads once, not
+	// every area's rows or every national label
+	result := calculate(t, h, "scope=national&occupation=Registered+Nurses")
+	if result.MatchingJobs != 3175390 || result.Location != "U.S." || result.AreaType != areaTypeNational || result.Scope != scopeNational {
+		t.Errorf("expected the national row only, got %+v", result)
+	}
+
+	// The state view matches the state row alone, by name or abbreviation
+	for _, query := range []string{"scope=state&location=Michigan", "scope=state&location=mi", "scope=state&areaCode=26"} {
+		result = calculate(t, h, query+"&occupation=Registered+Nurses")
+		if result.MatchingJobs != 100000 || result.Location != "Michigan" || result.Scope != scopeState {
+			t.Errorf("%s: expected the Michigan row only, got %+v", query, result)
+		}
+	}
+
+	// The area scope stays the default and still matches by pattern
+	result = calculate(t, h, "location=Michigan&locationMatch=contains&occupation=Registered+Nurses")
+	if result.MatchingJobs != 103000 || result.Scope != scopeArea {
+		t.Errorf("expected Michigan and its nonmetro area, got %+v", result)
+	}
+}
+
+func TestCalculateHandlerRejectsInvalidScope(t *testing.T) {
+	h := newTestAreaHandlers(t)
+	for _, query := range []string{
+		"scope=world",
+		"scope=national&location=Michigan",
+		"scope=national&areaCode=99",
+		"scope=national&zip=48201",
+		"scope=state",
+		"scope=state&location=Detroit-Warren-Dearborn,+MI",
+		"scope=state&location=Ohio",
+		"scope=state&areaCode=19820",
+		"scope=state&location=Michigan&areaCode=99",
+		"scope=state&location=Michigan&radiusMiles=60",
+		"occupation=Registered+Nurses",
+	} {
+		rr := httptest.NewRecorder()
+		h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
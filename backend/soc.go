@@ -0,0 +1,170 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+)
+
+// SOC hierarchy levels. Every detailed occupation code (e.g. 15-1252) belongs
+// to one broad group (15-1250), one minor group (15-1200) and one major group
+// (15-0000), all derivable from the code itself.
+const (
+	socMajor    = "major"
+	socMinor    = "minor"
+	socBroad    = "broad"
+	socDetailed = "detailed"
+)
+
+// socLevels lists the hierarchy levels from the top down
+var socLevels = []string{socMajor, socMinor, socBroad, socDetailed}
+
+// socTwoDigitMinorGroups are the SOC 2018 minor groups identified by two
+// digits after the dash instead of one (15-1200, 31-1100, 51-5100)
+var socTwoDigitMinorGroups = map[string]bool{"15-12": true, "31-11": true, "51-51": true}
+
+// socCodePattern matches a well-formed SOC code
+var socCodePattern = regexp.MustCompile(`^\d{2}-\d{4}$`)
+
+// socMajorGroupTitles are the SOC 2018 major group titles, used when the
+// loaded data carries no occupation_groups titles
+var socMajorGroupTitles = map[string]string{
+	"11-0000": "Management Occupations",
+	"13-0000": "Business and Financial Operations Occupations",
+	"15-0000": "Computer and Mathematical Occupations",
+	"17-0000": "Architecture and Engineering Occupations",
+	"19-0000": "Life, Physical, and Social Science Occupations",
+	"21-0000": "Community and Social Service Occupations",
+	"23-0000": "Legal Occupations",
+	"25-0000": "Educational Instruction and Library Occupations",
+	"27-0000": "Arts, Design, Entertainment, Sports, and Media Occupations",
+	"29-0000": "Healthcare Practitioners and Technical Occupations",
+	"31-0000": "Healthcare Support Occupations",
+	"33-0000": "Protective Service Occupations",
+	"35-0000": "Food Preparation and Serving Related Occupations",
+	"37-0000": "Building and Grounds Cleaning and Maintenance Occupations",
+	"39-0000": "Personal Care and Service Occupations",
+	"41-0000": "Sales and Related Occupations",
+	"43-0000": "Office and Administrative Support Occupations",
+	"45-0000": "Farming, Fishing, and Forestry Occupations",
+	"47-0000": "Construction and Extraction Occupations",
+	"49-0000": "Installation, Maintenance, and Repair Occupations",
+	"51-0000": "Production Occupations",
+	"53-0000": "Transportation and Material Moving Occupations",
+	"55-0000": "Military Specific Occupations",
+}
+
+// socAncestors returns the major, minor and broad group codes of a detailed
+// occupation code. ok is false for malformed codes.
+func socAncestors(code string) (major, minor, broad string, ok bool) {
+	if !socCodePattern.MatchString(code) {
+		return "", "", "", false
+	}
+	major = code[:3] + "0000"
+	if socTwoDigitMinorGroups[code[:5]] {
+		minor = code[:5] + "00"
+	} else {
+		minor = code[:4] + "000"
+	}
+	broad = code[:6] + "0"
+	return major, minor, broad, true
+}
+
+// socGroupPrefix returns the occ_code prefix shared by every occupation in
+// the group code and the group's level. A detailed code is its own prefix.
+func socGroupPrefix(code string) (prefix, level string, ok bool) {
+	if !socCodePattern.MatchString(code) {
+		return "", "", false
+	}
+	switch {
+	case code[3:] == "0000":
+		return code[:3], socMajor, true
+	case socTwoDigitMinorGroups[code[:5]] && code[5:] == "00":
+		return code[:5], socMinor, true
+	case code[4:] == "000":
+		return code[:4], socMinor, true
+	case code[6:] == "0":
+		return code[:6], socBroad, true
+	default:
+		return code, socDetailed, true
+	}
+}
+
+// OccupationGroup is a node of the SOC hierarchy returned by
+// /api/occupation-groups. Detailed occupations are the leaves.
+type OccupationGroup struct {
+	Code     string            `json:"code"`
+	Title    string            `json:"title"`
+	Level    string            `json:"level"`
+	Children []OccupationGroup `json:"children,omitempty"`
+}
+
+// buildOccupationGroupTree arranges the loaded detailed occupations under their
+// broad, minor and major groups, down to maxLevel. Group titles come from
+// titles (occupation_groups), then socMajorGroupTitles; untitled groups are
+// labelled with their code. Occupations with malformed codes are skipped.
+func buildOccupationGroupTree(occupations []Occupation, titles map[string]string, maxLevel string) []OccupationGroup {
+	depth := len(socLevels)
+	for i, level := range socLevels {
+		if level == maxLevel {
+			depth = i + 1
+		}
+	}
+	title := func(code string) string {
+		if t, ok := titles[code]; ok && t != "" {
+			return t
+		}
+		if t, ok := socMajorGroupTitles[code]; ok {
+			return t
+		}
+		return code
+	}
+
+	root := &groupNode{children: make(map[string]*groupNode)}
+	for _, o := range occupations {
+		major, minor, broad, ok := socAncestors(o.Code)
+		if !ok {
+			continue
+		}
+		node := root
+		path := []OccupationGroup{
+			{Code: major, Title: title(major), Level: socMajor},
+			{Code: minor, Title: title(minor), Level: socMinor},
+			{Code: broad, Title: title(broad), Level: socBroad},
+			{Code: o.Code, Title: o.Title, Level: socDetailed},
+		}
+		for _, g := range path[:depth] {
+			node = node.child(g)
+		}
+	}
+	return root.groups()
+}
+
+// groupNode accumulates the hierarchy before it is sorted into OccupationGroups
+type groupNode struct {
+	group    OccupationGroup
+	children map[string]*groupNode
+}
+
+func (n *groupNode) child(g OccupationGroup) *groupNode {
+	c, ok := n.children[g.Code]
+	if !ok {
+		c = &groupNode{group: g, children: make(map[string]*groupNode)}
+		n.children[g.Code] = c
+	}
+	return c
+}
+
+// groups returns the children ordered by code
+func (n *groupNode) groups() []OccupationGroup {
+	groups := make([]OccupationGroup, 0, len(n.children))
+	for _, c := range n.children {
+		g := c.group
+		g.Children = c.groups()
+		if len(g.Children) == 0 {
+			g.Children = nil
+		}
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Code < groups[j].Code })
+	return groups
+}
@@ -0,0 +1,61 @@
+package main
+
+import "testing"
+
+func TestSOCAncestors(t *testing.T) {
+	cases := []struct{ code, major, minor, broad string }{
+		{"29-1141", "29-0000", "29-1000", "29-1140"},
+		{"15-1252", "15-0000", "15-1200", "15-1250"},
+		{"15-2031", "15-0000", "15-2000", "15-2030"},
+		{"31-1122", "31-0000", "31-1100", "31-1120"},
+		{"51-5112", "51-0000", "51-5100", "51-5110"},
+	}
+	for _, c := range cases {
+		major, minor, broad, ok := socAncestors(c.code)
+		if !ok || major != c.major || minor != c.minor || broad != c.broad {
+			t.Errorf("%s: got %s %s %s (%v)", c.code, major, minor, broad, ok)
+		}
+	}
+	if _, _, _, ok := socAncestors("Nurses"); ok {
+		t.Error("expected a malformed code to be rejected")
+	}
+}
+
+func TestSOCGroupPrefix(t *testing.T) {
+	cases := []struct{ code, prefix, level string }{
+		{"15-0000", "15-", socMajor},
+		{"15-1200", "15-12", socMinor},
+		{"15-2000", "15-2", socMinor},
+		{"15-1250", "15-125", socBroad},
+		{"15-1252", "15-1252", socDetailed},
+	}
+	for _, c := range cases {
+		prefix, level, ok := socGroupPrefix(c.code)
+		if !ok || prefix != c.prefix || level != c.level {
+			t.Errorf("%s: got %q %q (%v)", c.code, prefix, level, ok)
+		}
+	}
+	if _, _, ok := socGroupPrefix("15-12"); ok {
+		t.Error("expected a partial code to be rejected")
+	}
+}
+
+func TestBuildOccupationGroupTree(t *testing.T) {
+	occupations := []Occupation{
+		{Code: "15-1253", Title: "Software Quality Assurance Analysts and Testers"},
+		{Code: "15-1252", Title: "Software Developers"},
+		{Code: "bogus", Title: "Ignored"},
+	}
+	tree := buildOccupationGroupTree(occupations, map[string]string{"15-1250": "Software and Web Developers, Programmers, and Testers"}, socDetailed)
+	if len(tree) != 1 {
+		t.Fatalf("expected one major group, got %+v", tree)
+	}
+	minor := tree[0].Children[0]
+	broad := minor.Children[0]
+	if minor.Title != "15-1200" || broad.Title != "Software and Web Developers, Programmers, and Testers" {
+		t.Errorf("unexpected titles: minor %q, broad %q", minor.Title, broad.Title)
+	}
+	if len(broad.Children) != 2 || broad.Children[0].Code != "15-1252" || broad.Children[0].Level != socDetailed {
+		t.Errorf("unexpected detailed occupations: %+v", broad.Children)
+	}
+}
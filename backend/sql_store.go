@@ -0,0 +1,573 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// SQLStore implements CareerDataStore on top of the career_data table.
+// The dialect selects Postgres or SQLite syntax.
+type SQLStore struct {
+	db      *sql.DB
+	dialect dialect
+}
+
+// NewPostgresStore creates a SQLStore for a Postgres database handle
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: postgresDialect}
+}
+
+// NewSQLiteStore creates a SQLStore for a SQLite database handle
+func NewSQLiteStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dialect: sqliteDialect}
+}
+
+// ListOccupations returns distinct occupation code/title pairs
+func (s *SQLStore) ListOccupations(ctx context.Context) ([]Occupation, error) {
+	query := "SELECT DISTINCT occ_code, occ_title FROM career_data WHERE occ_title IS NOT NULL AND occ_title != '' AND occ_title <> 'All Occupations' ORDER BY occ_title, occ_code" // exclude aggregate row
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var occupations []Occupation
+	for rows.Next() {
+		var o Occupation
+		if err := rows.Scan(&o.Code, &o.Title); err != nil {
+			return nil, err
+		}
+		occupations = append(occupations, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return occupations, nil
+}
+
+// ListAreas returns distinct area titles, excluding national areas
+func (s *SQLStore) ListAreas(ctx context.Context) ([]string, error) {
+	query := `
+        SELECT DISTINCT c.area_title, a.area_type, a.states
+        FROM career_data c
+        LEFT JOIN areas a ON a.area_title = c.area_title
+        WHERE a.area_type IS NULL OR a.area_type <> ` + s.dialect.placeholder(1) + `
+        ORDER BY c.area_title`
+	return s.queryAreaTitles(ctx, query, func(a Area) bool { return a.Type != areaTypeNational }, areaTypeNational)
+}
+
+// ListStates returns distinct state-level area titles
+func (s *SQLStore) ListStates(ctx context.Context) ([]string, error) {
+	query := `
+        SELECT DISTINCT c.area_title, a.area_type, a.states
+        FROM career_data c
+        LEFT JOIN areas a ON a.area_title = c.area_title
+        WHERE a.area_type IS NULL OR a.area_type = ` + s.dialect.placeholder(1) + `
+        ORDER BY c.area_title`
+	return s.queryAreaTitles(ctx, query, func(a Area) bool { return a.Type == areaTypeState }, areaTypeState)
+}
+
+// AreasForState returns all area titles relevant to a given state: the state
+// itself and every area listing it among its member states. Unknown states
+// match nothing.
+func (s *SQLStore) AreasForState(ctx context.Context, state string) ([]string, error) {
+	state, abbr, ok := lookupState(state)
+	if !ok {
+		return nil, nil
+	}
+	d := s.dialect
+	query := fmt.Sprintf(`
+        SELECT DISTINCT c.area_title, a.area_type, a.states
+        FROM career_data c
+        LEFT JOIN areas a ON a.area_title = c.area_title
+        WHERE a.area_title IS NULL
+           OR c.area_title = %[1]s
+           OR ',' || a.states || ',' LIKE '%%,' || %[2]s || ',%%'
+        ORDER BY c.area_title`, d.placeholder(1), d.placeholder(2))
+	return s.queryAreaTitles(ctx, query, func(a Area) bool {
+		return a.Title == state || containsString(a.States, abbr)
+	}, state, abbr)
+}
+
+// queryAreaTitles scans a query selecting area titles with their areas table
+// type and states. Titles missing from the areas table (rows loaded by a
+// manual import) are classified with newArea and kept only if keep accepts
+// them; the query itself filters the rest.
+func (s *SQLStore) queryAreaTitles(ctx context.Context, query string, keep func(Area) bool, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		var areaType, states sql.NullString
+		if err := rows.Scan(&title, &areaType, &states); err != nil {
+			return nil, err
+		}
+		if !areaType.Valid && !keep(newArea("", title, "", "")) {
+			continue
+		}
+		titles = append(titles, title)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return titles, nil
+}
+
+// Areas returns the areas table keyed by area title. Areas holding career
+// data but missing from the table are classified from their titles.
+func (s *SQLStore) Areas(ctx context.Context) (map[string]Area, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT area_title, area_code, area_type, states FROM areas")
+	if err != nil {
+		return nil, fmt.Errorf("error querying areas: %v", err)
+	}
+	defer rows.Close()
+
+	areas := make(map[string]Area)
+	for rows.Next() {
+		var a Area
+		var code sql.NullString
+		var states string
+		if err := rows.Scan(&a.Title, &code, &a.Type, &states); err != nil {
+			return nil, fmt.Errorf("error scanning areas: %v", err)
+		}
+		a.Code = code.String
+		if states != "" {
+			a.States = strings.Split(states, ",")
+		}
+		areas[a.Title] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating areas: %v", err)
+	}
+
+	missing, err := s.db.QueryContext(ctx, `
+        SELECT c.area_title, MAX(c.area_code)
+        FROM career_data c
+        LEFT JOIN areas a ON a.area_title = c.area_title
+        WHERE a.area_title IS NULL
+        GROUP BY c.area_title`)
+	if err != nil {
+		return nil, fmt.Errorf("error querying unclassified areas: %v", err)
+	}
+	defer missing.Close()
+	for missing.Next() {
+		var title string
+		var code sql.NullString
+		if err := missing.Scan(&title, &code); err != nil {
+			return nil, fmt.Errorf("error scanning unclassified areas: %v", err)
+		}
+		areas[title] = newArea(code.String, title, "", "")
+	}
+	if err := missing.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating unclassified areas: %v", err)
+	}
+	return areas, nil
+}
+
+// MatchingRows returns the career_data rows selected by buildQuery
+func (s *SQLStore) MatchingRows(ctx context.Context, filters Filters) ([]careerRow, error) {
+	query, args := buildQuery(s.dialect, filters)
+	return s.queryCareerRows(ctx, query, args...)
+}
+
+// HistoryRows returns the career_data_history rows selected by buildHistoryQuery
+func (s *SQLStore) HistoryRows(ctx context.Context, filters Filters) ([]careerRow, error) {
+	query, args := buildHistoryQuery(s.dialect, filters)
+	return s.queryCareerRows(ctx, query, args...)
+}
+
+// queryCareerRows scans a query selecting the careerRow columns in table order
+func (s *SQLStore) queryCareerRows(ctx context.Context, query string, args ...interface{}) ([]careerRow, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying matching jobs: %v", err)
+	}
+	defer rows.Close()
+
+	var matches []careerRow
+	for rows.Next() {
+		var r careerRow
+		var areaCode, occTitle, education, experience sql.NullString
+		if err := rows.Scan(&r.Year, &areaCode, &r.AreaTitle, &r.OccCode, &occTitle, &education, &experience,
+			&r.TotEmp, &r.Median, &r.Pct10, &r.Pct25, &r.Pct75, &r.Pct90,
+			&r.HMedian, &r.HPct10, &r.HPct25, &r.HPct75, &r.HPct90, &r.TopCoded, &r.HTopCoded); err != nil {
+			return nil, fmt.Errorf("error scanning matching jobs: %v", err)
+		}
+		r.AreaCode, r.OccTitle, r.Education, r.Experience = areaCode.String, occTitle.String, education.String, experience.String
+		matches = append(matches, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating matching jobs: %v", err)
+	}
+	return matches, nil
+}
+
+// NationalTotal returns the national employment total.
+// Some datasets include many '00-0000' rows (one per area). We want the SINGLE national total, which should have the
+// largest tot_emp for that occ_code. Ordering by tot_emp DESC ensures we pick the correct national aggregate even if
+// area_title filters (e.g., 'U.S.') vary or were transformed during preprocessing.
+func (s *SQLStore) NationalTotal(ctx context.Context, year int) (int, error) {
+	var total int
+	err := s.db.QueryRowContext(ctx,
+		"SELECT tot_emp FROM career_data WHERE occ_code = '00-0000' AND data_year = "+s.dialect.placeholder(1)+" ORDER BY tot_emp DESC LIMIT 1",
+		year).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("error querying total jobs: %v", err)
+	}
+	return total, nil
+}
+
+// NationalArea returns the area title of the row NationalTotal reads
+func (s *SQLStore) NationalArea(ctx context.Context, year int) (string, error) {
+	var title string
+	err := s.db.QueryRowContext(ctx,
+		"SELECT area_title FROM career_data WHERE occ_code = '00-0000' AND data_year = "+s.dialect.placeholder(1)+" ORDER BY tot_emp DESC LIMIT 1",
+		year).Scan(&title)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error querying national area: %v", err)
+	}
+	return title, nil
+}
+
+// RegionalTotal returns the summed detailed-occupation employment of the
+// areas selected by the location filters
+func (s *SQLStore) RegionalTotal(ctx context.Context, filters Filters) (int, error) {
+	where, args := buildWhereClause(s.dialect, filters.regionScope())
+	var total sql.NullInt64
+	err := s.db.QueryRowContext(ctx, "SELECT SUM(tot_emp) FROM career_data WHERE 1=1"+where, args...).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("error querying regional total jobs: %v", err)
+	}
+	return int(total.Int64), nil
+}
+
+// AreaTotals returns the summed detailed-occupation employment per area title
+func (s *SQLStore) AreaTotals(ctx context.Context, year int) (map[string]int, error) {
+	where, args := buildWhereClause(s.dialect, Filters{Year: year})
+	rows, err := s.db.QueryContext(ctx, "SELECT area_title, SUM(tot_emp) FROM career_data WHERE 1=1"+where+" GROUP BY area_title", args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying area totals: %v", err)
+	}
+	defer rows.Close()
+
+	totals := make(map[string]int)
+	for rows.Next() {
+		var title string
+		var total sql.NullInt64
+		if err := rows.Scan(&title, &total); err != nil {
+			return nil, fmt.Errorf("error scanning area totals: %v", err)
+		}
+		totals[title] = int(total.Int64)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating area totals: %v", err)
+	}
+	return totals, nil
+}
+
+// OccupationGroupTitles returns the occupation_groups titles keyed by code
+func (s *SQLStore) OccupationGroupTitles(ctx context.Context) (map[string]string, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT code, title FROM occupation_groups")
+	if err != nil {
+		return nil, fmt.Errorf("error querying occupation groups: %v", err)
+	}
+	defer rows.Close()
+
+	titles := make(map[string]string)
+	for rows.Next() {
+		var code, title string
+		if err := rows.Scan(&code, &title); err != nil {
+			return nil, fmt.Errorf("error scanning occupation groups: %v", err)
+		}
+		titles[code] = title
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating occupation groups: %v", err)
+	}
+	return titles, nil
+}
+
+// PriceParities returns the regional_price_parities rows keyed by area title
+func (s *SQLStore) PriceParities(ctx context.Context) (map[string]float64, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT area_title, rpp FROM regional_price_parities")
+	if err != nil {
+		return nil, fmt.Errorf("error querying price parities: %v", err)
+	}
+	defer rows.Close()
+
+	parities := make(map[string]float64)
+	for rows.Next() {
+		var title string
+		var rpp float64
+		if err := rows.Scan(&title, &rpp); err != nil {
+			return nil, fmt.Errorf("error scanning price parities: %v", err)
+		}
+		parities[title] = rpp
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price parities: %v", err)
+	}
+	return parities, nil
+}
+
+// CPIIndex returns the cpi_index rows keyed by year
+func (s *SQLStore) CPIIndex(ctx context.Context) (map[int]float64, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT year, cpi FROM cpi_index")
+	if err != nil {
+		return nil, fmt.Errorf("error querying CPI index: %v", err)
+	}
+	defer rows.Close()
+
+	index := make(map[int]float64)
+	for rows.Next() {
+		var year int
+		var cpi float64
+		if err := rows.Scan(&year, &cpi); err != nil {
+			return nil, fmt.Errorf("error scanning CPI index: %v", err)
+		}
+		index[year] = cpi
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating CPI index: %v", err)
+	}
+	return index, nil
+}
+
+// ListYears returns the distinct data years, newest first
+func (s *SQLStore) ListYears(ctx context.Context) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT DISTINCT data_year FROM career_data ORDER BY data_year DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var years []int
+	for rows.Next() {
+		var y int
+		if err := rows.Scan(&y); err != nil {
+			return nil, err
+		}
+		years = append(years, y)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return years, nil
+}
+
+// queryStrings runs a query returning a single text column and collects the values
+func (s *SQLStore) queryStrings(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Tables sharing the career_data layout
+const (
+	careerDataTable    = "career_data"
+	careerHistoryTable = "career_data_history"
+)
+
+// withTx runs fn in a transaction, committing only when it succeeds
+func withTx(ctx context.Context, db *sql.DB, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// upsertCareerRows inserts or updates rows of table (career_data or
+// career_data_history) keyed on (data_year, area_title, occ_code) in a
+// single transaction and reports how many keys were new versus replaced
+func upsertCareerRows(ctx context.Context, db *sql.DB, d dialect, table string, rows []careerRow) (inserted, updated int, err error) {
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		inserted, updated, err = insertCareerRows(ctx, tx, d, table, rows)
+		return err
+	})
+	return inserted, updated, err
+}
+
+// insertCareerRows upserts rows of table within tx, reporting how many keys
+// were new versus replaced
+func insertCareerRows(ctx context.Context, tx *sql.Tx, d dialect, table string, rows []careerRow) (inserted, updated int, err error) {
+	existing := make(map[careerKey]bool)
+	keys, err := tx.QueryContext(ctx, "SELECT data_year, area_title, occ_code FROM "+table)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error reading existing %s keys: %v", table, err)
+	}
+	for keys.Next() {
+		var k careerKey
+		if err := keys.Scan(&k.Year, &k.AreaTitle, &k.OccCode); err != nil {
+			keys.Close()
+			return 0, 0, fmt.Errorf("error reading existing %s keys: %v", table, err)
+		}
+		existing[k] = true
+	}
+	keys.Close()
+	if err := keys.Err(); err != nil {
+		return 0, 0, fmt.Errorf("error reading existing %s keys: %v", table, err)
+	}
+
+	placeholders := make([]string, 20)
+	for i := range placeholders {
+		placeholders[i] = d.placeholder(i + 1)
+	}
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO `+table+`
+		(data_year, area_title, occ_code, occ_title, education, experience, tot_emp, a_median, a_pct10, a_pct25, a_pct75, a_pct90, area_code,
+			h_median, h_pct10, h_pct25, h_pct75, h_pct90, a_top_coded, h_top_coded)
+		VALUES (`+strings.Join(placeholders, ", ")+`)
+		ON CONFLICT (data_year, area_title, occ_code) DO UPDATE SET
+			occ_title = excluded.occ_title,
+			education = excluded.education,
+			experience = excluded.experience,
+			tot_emp = excluded.tot_emp,
+			a_median = excluded.a_median,
+			a_pct10 = excluded.a_pct10,
+			a_pct25 = excluded.a_pct25,
+			a_pct75 = excluded.a_pct75,
+			a_pct90 = excluded.a_pct90,
+			area_code = excluded.area_code,
+			h_median = excluded.h_median,
+			h_pct10 = excluded.h_pct10,
+			h_pct25 = excluded.h_pct25,
+			h_pct75 = excluded.h_pct75,
+			h_pct90 = excluded.h_pct90,
+			a_top_coded = excluded.a_top_coded,
+			h_top_coded = excluded.h_top_coded`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error preparing %s upsert: %v", table, err)
+	}
+	defer stmt.Close()
+
+	for _, r := range rows {
+		if _, err := stmt.ExecContext(ctx,
+			r.Year, r.AreaTitle, r.OccCode, nullString(r.OccTitle), nullString(r.Education), nullString(r.Experience),
+			nullInt(r.TotEmp), nullInt(r.Median), nullInt(r.Pct10), nullInt(r.Pct25), nullInt(r.Pct75), nullInt(r.Pct90),
+			nullString(r.AreaCode),
+			r.HMedian, r.HPct10, r.HPct25, r.HPct75, r.HPct90, int(r.TopCoded), int(r.HTopCoded),
+		); err != nil {
+			return 0, 0, fmt.Errorf("error upserting %d %s / %s: %v", r.Year, r.AreaTitle, r.OccCode, err)
+		}
+		key := r.key()
+		if existing[key] {
+			updated++
+		} else {
+			inserted++
+			existing[key] = true
+		}
+	}
+	return inserted, updated, nil
+}
+
+// nullString maps an empty string to SQL NULL
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
+// nullInt rounds a nullable float for the INTEGER wage and employment columns
+func nullInt(v sql.NullFloat64) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(math.Round(v.Float64)), Valid: v.Valid}
+}
+
+// upsertOccupationGroups stores SOC group titles keyed on code, replacing
+// titles from earlier releases
+func upsertOccupationGroups(ctx context.Context, db *sql.DB, d dialect, groups []OccupationGroup) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error { return insertOccupationGroups(ctx, tx, d, groups) })
+}
+
+// insertOccupationGroups upserts SOC group titles by code within tx
+func insertOccupationGroups(ctx context.Context, tx *sql.Tx, d dialect, groups []OccupationGroup) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO occupation_groups (code, level, title)
+		VALUES (`+d.placeholder(1)+`, `+d.placeholder(2)+`, `+d.placeholder(3)+`)
+		ON CONFLICT (code) DO UPDATE SET level = excluded.level, title = excluded.title`)
+	if err != nil {
+		return fmt.Errorf("error preparing occupation_groups upsert: %v", err)
+	}
+	defer stmt.Close()
+
+	for _, g := range groups {
+		if _, err := stmt.ExecContext(ctx, g.Code, g.Level, g.Title); err != nil {
+			return fmt.Errorf("error upserting occupation group %s: %v", g.Code, err)
+		}
+	}
+	return nil
+}
+
+// upsertPriceParities inserts or replaces regional price parities by area title
+func upsertPriceParities(ctx context.Context, db *sql.DB, d dialect, parities map[string]float64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error { return insertPriceParities(ctx, tx, d, parities) })
+}
+
+// insertPriceParities upserts regional price parities by area title within tx
+func insertPriceParities(ctx context.Context, tx *sql.Tx, d dialect, parities map[string]float64) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO regional_price_parities (area_title, rpp)
+		VALUES (`+d.placeholder(1)+`, `+d.placeholder(2)+`)
+		ON CONFLICT (area_title) DO UPDATE SET rpp = excluded.rpp`)
+	if err != nil {
+		return fmt.Errorf("error preparing regional_price_parities upsert: %v", err)
+	}
+	defer stmt.Close()
+
+	for title, rpp := range parities {
+		if _, err := stmt.ExecContext(ctx, title, rpp); err != nil {
+			return fmt.Errorf("error upserting price parity for %s: %v", title, err)
+		}
+	}
+	return nil
+}
+
+// upsertCPIIndex inserts or replaces annual CPI values by year
+func upsertCPIIndex(ctx context.Context, db *sql.DB, d dialect, index map[int]float64) error {
+	return withTx(ctx, db, func(tx *sql.Tx) error { return insertCPIIndex(ctx, tx, d, index) })
+}
+
+// insertCPIIndex upserts annual CPI values by year within tx
+func insertCPIIndex(ctx context.Context, tx *sql.Tx, d dialect, index map[int]float64) error {
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO cpi_index (year, cpi)
+		VALUES (`+d.placeholder(1)+`, `+d.placeholder(2)+`)
+		ON CONFLICT (year) DO UPDATE SET cpi = excluded.cpi`)
+	if err != nil {
+		return fmt.Errorf("error preparing cpi_index upsert: %v", err)
+	}
+	defer stmt.Close()
+
+	for year, cpi := range index {
+		if _, err := stmt.ExecContext(ctx, year, cpi); err != nil {
+			return fmt.Errorf("error upserting CPI for %d: %v", year, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestSQLiteStore returns a migrated in-memory SQLite store seeded with testCareerCSV
+func newTestSQLiteStore(t *testing.T) *SQLStore {
+	t.Helper()
+	db, err := initSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("initSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	rows, err := readCareerCSV(strings.NewReader(testCareerCSV))
+	if err != nil {
+		t.Fatalf("readCareerCSV: %v", err)
+	}
+	if _, _, err := upsertCareerRows(context.Background(), db, sqliteDialect, careerDataTable, rows); err != nil {
+		t.Fatalf("upsertCareerRows: %v", err)
+	}
+	if err := upsertAreas(context.Background(), db, sqliteDialect, areasFromRows(rows)); err != nil {
+		t.Fatalf("upsertAreas: %v", err)
+	}
+	return NewSQLiteStore(db)
+}
+
+func TestBuildQueryDialects(t *testing.T) {
+	filters := Filters{Location: "Michigan", LocationMatch: matchContains, Occupation: "Nurse", MinSalary: 80000}
+
+	pg, args := buildQuery(postgresDialect, filters)
+	if !strings.Contains(pg, "area_title ILIKE $1") || !strings.Contains(pg, "occ_title ILIKE $2") {
+		t.Errorf("unexpected postgres query: %s", pg)
+	}
+	// The salary threshold is applied by aggregateRows, not the query
+	if len(args) != 2 || strings.Contains(pg, "a_pct90 >=") {
+		t.Errorf("expected no salary predicate, got %d args: %s", len(args), pg)
+	}
+
+	history, _ := buildHistoryQuery(postgresDialect, Filters{Location: "Michigan", Year: 2023})
+	if !strings.Contains(history, "FROM career_data_history") || strings.Contains(history, "data_year =") {
+		t.Errorf("expected an all-years history query: %s", history)
+	}
+
+	lite, _ := buildQuery(sqliteDialect, filters)
+	if !strings.Contains(lite, "area_title LIKE ?1") || strings.Contains(lite, "$") {
+		t.Errorf("unexpected sqlite query: %s", lite)
+	}
+
+	// Locations match exactly by default and aggregate rows are never selected
+	exact, args := buildQuery(postgresDialect, Filters{Location: "Michigan"})
+	if !strings.Contains(exact, "LOWER(area_title) = LOWER($1)") || !strings.Contains(exact, "occ_code <> '00-0000'") || args[0] != "Michigan" {
+		t.Errorf("unexpected exact location query: %s %v", exact, args)
+	}
+}
+
+func TestOpenStoreSeedsSQLiteAreas(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "combined_career_data.csv")
+	if err := os.WriteFile(csvPath, []byte(testCareerCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, closeStore, err := openStore(storeConfig{DataSource: "sqlite", SQLitePath: filepath.Join(dir, "career_data.db"), CSVPath: csvPath})
+	if err != nil {
+		t.Fatalf("openStore: %v", err)
+	}
+	defer closeStore()
+	mem := newTestMemoryStore(t)
+
+	for name, pair := range map[string][2]func(context.Context) ([]string, error){
+		"areas":  {store.ListAreas, mem.ListAreas},
+		"states": {store.ListStates, mem.ListStates},
+	} {
+		got, _ := pair[0](ctx)
+		want, _ := pair[1](ctx)
+		if len(got) == 0 || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: seeded sqlite %v, memory %v", name, got, want)
+		}
+	}
+	got, _ := store.AreasForState(ctx, "Michigan")
+	want, _ := mem.AreasForState(ctx, "Michigan")
+	if len(got) == 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("areas for state: seeded sqlite %v, memory %v", got, want)
+	}
+}
+
+func TestSQLStoreClassifiesAreasMissingFromTable(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLiteStore(t)
+	mem := newTestMemoryStore(t)
+	// Rows imported by hand (README step 9) have no areas entries
+	if _, err := store.db.Exec("DELETE FROM areas"); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, pair := range map[string][2]func(context.Context) ([]string, error){
+		"areas":  {store.ListAreas, mem.ListAreas},
+		"states": {store.ListStates, mem.ListStates},
+	} {
+		got, err := pair[0](ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := pair[1](ctx)
+		if len(got) == 0 || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: unclassified sqlite %v, memory %v", name, got, want)
+		}
+	}
+	got, _ := store.AreasForState(ctx, "Michigan")
+	want, _ := mem.AreasForState(ctx, "Michigan")
+	if len(got) == 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("areas for state: unclassified sqlite %v, memory %v", got, want)
+	}
+	areas, err := store.Areas(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := areas["Michigan"]; a.Type != areaTypeState || !reflect.DeepEqual(a.States, []string{"MI"}) {
+		t.Errorf("expected Michigan classified as a state, got %+v", a)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	if err := migrate(context.Background(), store.db, sqliteDialect); err != nil {
+		t.Fatalf("second migrate: %v", err)
+	}
+	var versions int
+	if err := store.db.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&versions); err != nil {
+		t.Fatal(err)
+	}
+	if versions != len(migrations) {
+		t.Errorf("expected %d recorded migrations, got %d", len(migrations), versions)
+	}
+
+	// UNIQUE (data_year, area_title, occ_code) is enforced
+	_, err := store.db.Exec("INSERT INTO career_data (area_title, occ_code) VALUES ('Michigan', '29-1141')")
+	if err == nil {
+		t.Error("expected duplicate (data_year, area_title, occ_code) to be rejected")
+	}
+}
+
+func TestSQLiteStoreMatchesMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	lite := newTestSQLiteStore(t)
+	mem := newTestMemoryStore(t)
+
+	cases := []Filters{
+		{Location: "Michigan"},
+		{Location: "michigan", Occupation: "nurse"},
+		{Location: "Michigan", Occupation: "nurse", Education: "Bachelor's degree"},
+		{Location: "Michigan", LocationMatch: matchContains, Experience: "Less than 5 years"},
+		{Location: "MI", LocationMatch: matchContains, MinSalary: 100000},
+		{Location: "Michigan", LocationMatch: matchPrefix},
+		{Location: "Nowhere"},
+		{Location: "Michigan", Occupation: "registered nurses", OccupationMatch: matchExact},
+		{Location: "Michigan", Occupation: "Nurse", OccupationMatch: matchPrefix},
+		{Location: "Michigan", OccCode: "29-1151"},
+		{Location: "Michigan", OccGroup: "29-0000"},
+		{Location: "Michigan", OccGroup: "15-1200"},
+		{Areas: []string{"Detroit-Warren-Dearborn, MI", "Toledo, OH"}, Occupation: "nurse"},
+	}
+	for _, f := range cases {
+		want := aggregateFor(t, mem, f)
+		got := aggregateFor(t, lite, f)
+		if got.MatchingJobs != want.MatchingJobs || got.EstimatedMatchingJobs != want.EstimatedMatchingJobs || got.Median.Valid != want.Median.Valid ||
+			math.Abs(got.Median.Float64-want.Median.Float64) > 1e-6 {
+			t.Errorf("%+v: sqlite %+v, memory %+v", f, got, want)
+		}
+	}
+
+	gotOccupations, _ := lite.ListOccupations(ctx)
+	wantOccupations, _ := mem.ListOccupations(ctx)
+	if !reflect.DeepEqual(gotOccupations, wantOccupations) {
+		t.Errorf("occupations: sqlite %v, memory %v", gotOccupations, wantOccupations)
+	}
+
+	for name, pair := range map[string][2]func(context.Context) ([]string, error){
+		"areas":  {lite.ListAreas, mem.ListAreas},
+		"states": {lite.ListStates, mem.ListStates},
+	} {
+		got, _ := pair[0](ctx)
+		want, _ := pair[1](ctx)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: sqlite %v, memory %v", name, got, want)
+		}
+	}
+
+	gotAreas, _ := lite.AreasForState(ctx, "Michigan")
+	wantAreas, _ := mem.AreasForState(ctx, "Michigan")
+	if !reflect.DeepEqual(gotAreas, wantAreas) {
+		t.Errorf("areas for state: sqlite %v, memory %v", gotAreas, wantAreas)
+	}
+	gotMetadata, _ := lite.Areas(ctx)
+	wantMetadata, _ := mem.Areas(ctx)
+	if !reflect.DeepEqual(gotMetadata, wantMetadata) {
+		t.Errorf("area metadata: sqlite %v, memory %v", gotMetadata, wantMetadata)
+	}
+
+	national, err := lite.NationalTotal(ctx, 2023)
+	if err != nil || national != 151853870 {
+		t.Errorf("unexpected national total %d (%v)", national, err)
+	}
+	nationalArea, err := lite.NationalArea(ctx, 2023)
+	memNationalArea, _ := mem.NationalArea(ctx, 2023)
+	if err != nil || nationalArea != "U.S." || memNationalArea != nationalArea {
+		t.Errorf("national area: sqlite %q (%v), memory %q", nationalArea, err, memNationalArea)
+	}
+	if empty, err := lite.NationalArea(ctx, 1999); err != nil || empty != "" {
+		t.Errorf("expected no national area for 1999, got %q (%v)", empty, err)
+	}
+	scope := Filters{Location: "Michigan", LocationMatch: matchPrefix, Year: 2023}
+	regional, _ := lite.RegionalTotal(ctx, scope)
+	memRegional, _ := mem.RegionalTotal(ctx, scope)
+	if regional != memRegional || regional != 205000 {
+		t.Errorf("regional total: sqlite %d, memory %d", regional, memRegional)
+	}
+	scope = Filters{Areas: []string{"Detroit-Warren-Dearborn, MI", "Toledo, OH"}, Year: 2023}
+	regional, _ = lite.RegionalTotal(ctx, scope)
+	memRegional, _ = mem.RegionalTotal(ctx, scope)
+	if regional != memRegional || regional != 48000 {
+		t.Errorf("regional total of areas: sqlite %d, memory %d", regional, memRegional)
+	}
+	areaTotals, _ := lite.AreaTotals(ctx, 2023)
+	memAreaTotals, _ := mem.AreaTotals(ctx, 2023)
+	if !reflect.DeepEqual(areaTotals, memAreaTotals) || areaTotals["Michigan"] != 202000 {
+		t.Errorf("area totals: sqlite %v, memory %v", areaTotals, memAreaTotals)
+	}
+}
+
+func TestCheckSchemaRequiresEveryMigration(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	ctx := context.Background()
+
+	if err := checkSchema(ctx, db); err == nil {
+		t.Error("expected an unmigrated database to be rejected")
+	}
+	all := migrations
+	migrations = all[:len(all)-1]
+	err = migrate(ctx, db, sqliteDialect)
+	migrations = all
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSchema(ctx, db); err == nil || !strings.Contains(err.Error(), "DB_MIGRATE") {
+		t.Errorf("expected a missing migration to be reported, got %v", err)
+	}
+	if err := migrate(ctx, db, sqliteDialect); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkSchema(ctx, db); err != nil {
+		t.Errorf("expected a current schema to pass, got %v", err)
+	}
+}
+
+func TestMigrationAddsDataYearToExistingRows(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	ctx := context.Background()
+
+	all := migrations
+	migrations = all[:1]
+	err = migrate(ctx, db, sqliteDialect)
+	migrations = all
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO career_data (area_title, occ_code, tot_emp) VALUES ('Michigan', '29-1141', 100000)"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrate(ctx, db, sqliteDialect); err != nil {
+		t.Fatal(err)
+	}
+	years, err := NewSQLiteStore(db).ListYears(ctx)
+	if err != nil || len(years) != 1 || years[0] != defaultDataYear {
+		t.Fatalf("expected existing rows in %d, got %v (%v)", defaultDataYear, years, err)
+	}
+	// The same area/occupation may now be loaded for another release
+	if _, err := db.Exec("INSERT INTO career_data (data_year, area_title, occ_code) VALUES (2022, 'Michigan', '29-1141')"); err != nil {
+		t.Errorf("expected a second year to be accepted: %v", err)
+	}
+}
+
+func TestHistoryRowsMatchAcrossStores(t *testing.T) {
+	history := `DATA_YEAR,AREA_TITLE,OCC_CODE,OCC_TITLE,TOT_EMP,A_MEDIAN
+2021,Michigan,29-1141,Registered Nurses,90000,76000
+2022,Michigan,29-1141,Registered Nurses,95000,80000
+2022,Michigan,29-1151,Nurse Anesthetists,2000,190000
+2022,Ohio,29-1141,Registered Nurses,130000,78000
+`
+	rows, err := readCareerCSV(strings.NewReader(history))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	lite := newTestSQLiteStore(t)
+	if _, _, err := upsertCareerRows(ctx, lite.db, sqliteDialect, careerHistoryTable, rows); err != nil {
+		t.Fatal(err)
+	}
+	mem := &MemoryStore{history: rows}
+
+	filters := Filters{Location: "Michigan", Occupation: "Registered", Year: 2023}
+	for name, store := range map[string]CareerDataStore{"sqlite": lite, "memory": mem} {
+		history, err := store.HistoryRows(ctx, filters)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		trend := aggregateByYear(history, filters)
+		if len(trend) != 2 || trend[0].Year != 2021 || trend[1].Year != 2022 {
+			t.Fatalf("%s: unexpected years %+v", name, trend)
+		}
+		if trend[1].MatchingJobs.Float64 != 95000 || trend[1].Median.Float64 != 80000 {
+			t.Errorf("%s: unexpected 2022 aggregate %+v", name, trend[1])
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// CareerDataStore abstracts the career_data dataset so handlers do not depend
+// on a particular database. Implementations must be safe for concurrent use.
+type CareerDataStore interface {
+	// ListOccupations returns distinct occupation code/title pairs ordered by
+	// title, excluding the "All Occupations" aggregate row
+	ListOccupations(ctx context.Context) ([]Occupation, error)
+	// ListAreas returns distinct non-national area titles
+	ListAreas(ctx context.Context) ([]string, error)
+	// ListStates returns distinct state-level area titles
+	ListStates(ctx context.Context) ([]string, error)
+	// AreasForState returns every area title relevant to the given state
+	// name: the state and the areas listing it among their member states
+	AreasForState(ctx context.Context, state string) ([]string, error)
+	// Areas returns the area metadata (code, type, member states) keyed by
+	// area title
+	Areas(ctx context.Context) (map[string]Area, error)
+	// MatchingRows returns the rows satisfying every filter except the salary
+	// threshold, which aggregateRows applies so that both the legacy and the
+	// estimated matching counts can be derived
+	MatchingRows(ctx context.Context, filters Filters) ([]careerRow, error)
+	// NationalTotal returns the national employment total (occ_code '00-0000') for a data year
+	NationalTotal(ctx context.Context, year int) (int, error)
+	// NationalArea returns the area title of the row NationalTotal reads, or
+	// "" when the year has none
+	NationalArea(ctx context.Context, year int) (string, error)
+	// RegionalTotal returns the summed detailed-occupation employment of the
+	// areas selected by the location filters (Location, LocationMatch,
+	// AreaCode and Year), the same scope MatchingRows draws from
+	RegionalTotal(ctx context.Context, filters Filters) (int, error)
+	// AreaTotals returns the summed detailed-occupation employment of every
+	// area of a data year, keyed by area title
+	AreaTotals(ctx context.Context, year int) (map[string]int, error)
+	// OccupationGroupTitles returns SOC group titles keyed by group code
+	// (e.g. "15-1200"); groups without a stored title are omitted
+	OccupationGroupTitles(ctx context.Context) (map[string]string, error)
+	// PriceParities returns the regional price parities (100 = national
+	// average) keyed by area title; areas without one are omitted
+	PriceParities(ctx context.Context) (map[string]float64, error)
+	// CPIIndex returns the annual consumer price index keyed by year
+	CPIIndex(ctx context.Context) (map[int]float64, error)
+	// ListYears returns the loaded OEWS release years, newest first
+	ListYears(ctx context.Context) ([]int, error)
+	// HistoryRows returns the historical release rows satisfying every filter
+	// except the salary threshold and the year, for aggregation per year
+	HistoryRows(ctx context.Context, filters Filters) ([]careerRow, error)
+}
+
+// Occupation identifies an SOC occupation by code and title
+type Occupation struct {
+	Code  string `json:"code"`
+	Title string `json:"title"`
+}
+
+// defaultDataYear is the OEWS release year assumed for data loaded without an
+// explicit year (the original dataset was built from the May 2023 release)
+const defaultDataYear = 2023
+
+// JobAggregate holds the aggregated figures for rows matching a set of filters.
+// Fields are nullable because no rows may match.
+type JobAggregate struct {
+	MatchingJobs sql.NullFloat64
+	// EstimatedMatchingJobs weights each row's employment by the interpolated
+	// share of workers earning at least the salary threshold
+	EstimatedMatchingJobs sql.NullFloat64
+	Median                sql.NullFloat64
+	Pct10                 sql.NullFloat64
+	Pct25                 sql.NullFloat64
+	Pct75                 sql.NullFloat64
+	Pct90                 sql.NullFloat64
+	TotalEmp              sql.NullFloat64
+	// SalaryMethod names how the percentile wages were combined across rows
+	SalaryMethod string
+	// WageStatus classifies Pct10, Pct25, Median, Pct75 and Pct90 (in that
+	// order) as wageExact, wageTopCoded or wageSuppressed; empty when no
+	// rows qualified
+	WageStatus [5]string
+	// AnnualFromHourly is set when some annual wages were derived from
+	// hourly ones (hourly × 2080)
+	AnnualFromHourly bool
+	// Hourly wage percentiles, combined the same way as the annual ones
+	HourlyMedian sql.NullFloat64
+	HourlyPct10  sql.NullFloat64
+	HourlyPct25  sql.NullFloat64
+	HourlyPct75  sql.NullFloat64
+	HourlyPct90  sql.NullFloat64
+}
+
+// YearAggregate is a JobAggregate for a single release year
+type YearAggregate struct {
+	Year int
+	JobAggregate
+}
@@ -0,0 +1,113 @@
+package main
+
+import "database/sql"
+
+// OEWS publishes wages at or above a top-code cap as '#'. The cap was
+// $208,000/yr ($100/hr) through the May 2021 estimates and $239,200/yr
+// ($115/hr) from May 2022.
+const (
+	topCodeSymbol       = "#"
+	topCodeAnnual       = 239200
+	topCodeHourly       = 115
+	legacyTopCodeAnnual = 208000
+	legacyTopCodeHourly = 100
+	topCodeChangeYear   = 2022
+)
+
+// Wage statuses reported per SalaryInfo field
+const (
+	// wageExact is a published value (or a combination of published values)
+	wageExact = "exact"
+	// wageTopCoded is a lower bound: some contributing value was at the cap
+	wageTopCoded = "topCoded"
+	// wageSuppressed means no matching row published the value
+	wageSuppressed = "suppressed"
+)
+
+// Wage columns of the OEWS and CSV layouts, in rowPercentiles order
+var (
+	annualWageColumns = [5]string{"A_PCT10", "A_PCT25", "A_MEDIAN", "A_PCT75", "A_PCT90"}
+	hourlyWageColumns = [5]string{"H_PCT10", "H_PCT25", "H_MEDIAN", "H_PCT75", "H_PCT90"}
+)
+
+// readWages fills the annual and hourly percentiles of r from the named
+// cells. The top-code symbol becomes the year's cap and sets the matching
+// TopCoded/HTopCoded bit; every other cell goes through parse.
+func (r *careerRow) readWages(cell func(col string) string, parse func(col, raw string) (sql.NullFloat64, error)) error {
+	annualCap, hourlyCap := topCodeCaps(r.Year)
+	annual := [5]*sql.NullFloat64{&r.Pct10, &r.Pct25, &r.Median, &r.Pct75, &r.Pct90}
+	hourly := [5]*sql.NullFloat64{&r.HPct10, &r.HPct25, &r.HMedian, &r.HPct75, &r.HPct90}
+	for i := range annual {
+		for _, f := range []struct {
+			col  string
+			dst  *sql.NullFloat64
+			mask *uint8
+			cap  float64
+		}{
+			{annualWageColumns[i], annual[i], &r.TopCoded, annualCap},
+			{hourlyWageColumns[i], hourly[i], &r.HTopCoded, hourlyCap},
+		} {
+			raw := cell(f.col)
+			if raw == topCodeSymbol {
+				*f.dst = sql.NullFloat64{Float64: f.cap, Valid: true}
+				*f.mask |= 1 << i
+				continue
+			}
+			v, err := parse(f.col, raw)
+			if err != nil {
+				return err
+			}
+			*f.dst = v
+		}
+	}
+	return nil
+}
+
+// topCodeCaps returns the annual and hourly top-code caps of a release year
+func topCodeCaps(year int) (annual, hourly float64) {
+	if year < topCodeChangeYear {
+		return legacyTopCodeAnnual, legacyTopCodeHourly
+	}
+	return topCodeAnnual, topCodeHourly
+}
+
+// writeWages renders the annual then hourly percentiles of r for the CSV
+// layout, writing top-coded values as the OEWS symbol so they read back with
+// their flag
+func (r careerRow) writeWages(format func(sql.NullFloat64) string) []string {
+	var cells []string
+	for _, set := range []struct {
+		pcts [5]sql.NullFloat64
+		mask uint8
+	}{{rowPercentiles(r), r.TopCoded}, {rowHourlyPercentiles(r), r.HTopCoded}} {
+		for i, v := range set.pcts {
+			if set.mask&(1<<i) != 0 && v.Valid {
+				cells = append(cells, topCodeSymbol)
+			} else {
+				cells = append(cells, format(v))
+			}
+		}
+	}
+	return cells
+}
+
+// wageStatuses classifies each combined percentile (in rowPercentiles order)
+// of rows: suppressed when the aggregate is NULL, topCoded when any row's
+// value for that percentile is at the cap, otherwise exact
+func wageStatuses(rows []careerRow, pcts [5]sql.NullFloat64) [5]string {
+	var statuses [5]string
+	for i, p := range pcts {
+		statuses[i] = wageExact
+		if !p.Valid {
+			statuses[i] = wageSuppressed
+			continue
+		}
+		for _, r := range rows {
+			if r.TopCoded&(1<<i) != 0 && rowPercentiles(r)[i].Valid {
+				statuses[i] = wageTopCoded
+				break
+			}
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTopCodeCaps(t *testing.T) {
+	for year, want := range map[int][2]float64{2019: {208000, 100}, 2021: {208000, 100}, 2022: {239200, 115}, 2024: {239200, 115}} {
+		if annual, hourly := topCodeCaps(year); annual != want[0] || hourly != want[1] {
+			t.Errorf("%d: expected caps %v, got %v/%v", year, want, annual, hourly)
+		}
+	}
+}
+
+func TestTopCodedWagesQualifyAndAreFlagged(t *testing.T) {
+	// Physicians: the median and upper percentiles are all top-coded
+	rows, err := readCareerCSV(strings.NewReader("DATA_YEAR,AREA_TITLE,OCC_CODE,OCC_TITLE,TOT_EMP,A_PCT10,A_PCT25,A_MEDIAN,A_PCT75,A_PCT90,H_MEDIAN\n" +
+		"2023,Michigan,29-1229,Physicians All Other,8000,90000,180000,#,#,#,#\n" +
+		"2021,Michigan,29-1229,Physicians All Other,7500,85000,170000,#,#,*,#\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := rows[0]; r.Median.Float64 != 239200 || r.TopCoded != 1<<2|1<<3|1<<4 || r.HMedian.Float64 != 115 || r.HTopCoded != 1<<2 {
+		t.Errorf("unexpected 2023 row %+v", r)
+	}
+	if r := rows[1]; r.Median.Float64 != 208000 || r.Pct90.Valid || r.TopCoded != 1<<2|1<<3 {
+		t.Errorf("unexpected 2021 row %+v", r)
+	}
+
+	// A top-coded percentile counts as at least the cap
+	agg := aggregateRows(rows[:1], Filters{MinSalary: 230000})
+	if agg.MatchingJobs.Float64 != 8000 {
+		t.Errorf("expected the top-coded row to qualify, got %+v", agg)
+	}
+	if agg = aggregateRows(rows[:1], Filters{MinSalary: 250000}); agg.MatchingJobs.Float64 != 0 {
+		t.Errorf("expected a threshold above the cap not to be confirmed, got %+v", agg)
+	}
+
+	agg = aggregateRows(rows[1:], Filters{})
+	want := [5]string{wageExact, wageExact, wageTopCoded, wageTopCoded, wageSuppressed}
+	if agg.WageStatus != want {
+		t.Errorf("expected statuses %v, got %v", want, agg.WageStatus)
+	}
+	info := salaryInfoFrom(agg)
+	if info.Flags == nil || info.Flags.MedianSalary != wageTopCoded || info.Flags.Pct90Salary != wageSuppressed || info.Pct90Salary != 0 {
+		t.Errorf("unexpected salary info %+v", info)
+	}
+	if info := salaryInfoFrom(aggregateRows(nil, Filters{})); info.Flags != nil {
+		t.Errorf("expected no flags without matching rows, got %+v", info.Flags)
+	}
+
+	// The CSV layout keeps the flags through a write/read cycle
+	path := filepath.Join(t.TempDir(), "combined.csv")
+	if err := writeCareerCSVFile(path, rows); err != nil {
+		t.Fatal(err)
+	}
+	reread, err := readCareerCSVFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rows {
+		if reread[i].TopCoded != rows[i].TopCoded || reread[i].HTopCoded != rows[i].HTopCoded || reread[i].Median != rows[i].Median {
+			t.Errorf("row %d changed on round trip: %+v", i, reread[i])
+		}
+	}
+}
+
+func TestDeriveAnnualWagesCarriesTopCodeFlags(t *testing.T) {
+	r, derived := deriveAnnualWages(careerRow{Year: 2023, HMedian: wage(115), HTopCoded: 1 << 2})
+	if !derived || r.Median.Float64 != 239200 || r.TopCoded != 1<<2 {
+		t.Errorf("expected a top-coded derived median, got %+v", r)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// zipPlace is one crosswalk record: a ZIP code's county with the CBSA of
+// metropolitan counties and, optionally, the OEWS area code of the
+// nonmetropolitan area covering the county
+type zipPlace struct {
+	ZIP    string
+	City   string
+	State  string // USPS abbreviation
+	County string // five-digit county FIPS code
+	CBSA   string // empty for counties outside a metro area
+	Area   string // OEWS AREA code, e.g. a nonmetropolitan area
+}
+
+// ZIPCrosswalk resolves ZIP codes and cities to counties and CBSAs
+type ZIPCrosswalk struct {
+	byZIP  map[string][]zipPlace
+	byCity map[string][]zipPlace // keyed by cityKey
+}
+
+// zipPattern matches a five-digit ZIP code
+var zipPattern = regexp.MustCompile(`^\d{5}$`)
+
+// cityKey identifies a city within a state for the crosswalk index
+func cityKey(city, stateAbbr string) string {
+	return strings.ToLower(strings.TrimSpace(city)) + "|" + stateAbbr
+}
+
+// readZIPCrosswalkCSVFile opens and parses a ZIP crosswalk CSV file
+func readZIPCrosswalkCSVFile(path string) (*ZIPCrosswalk, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening ZIP crosswalk CSV: %v", err)
+	}
+	defer f.Close()
+
+	crosswalk, err := readZIPCrosswalkCSV(f)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+	return crosswalk, nil
+}
+
+// readZIPCrosswalkCSV parses a ZIP→county→CBSA crosswalk with ZIP, COUNTY,
+// CBSA, CITY and STATE columns (matched by header name, case-insensitive;
+// the HUD USPS_ZIP_PREF_CITY and USPS_ZIP_PREF_STATE names are accepted too)
+// and an optional AREA column with the OEWS area code of nonmetropolitan
+// counties. A ZIP spanning several counties has one row per county.
+func readZIPCrosswalkCSV(r io.Reader) (*ZIPCrosswalk, error) {
+	table, err := newCSVTable(r)
+	if err != nil {
+		return nil, err
+	}
+	table.alias("USPS_ZIP_PREF_CITY", "CITY")
+	table.alias("USPS_ZIP_PREF_STATE", "STATE")
+	if err := table.require("ZIP", "COUNTY", "CBSA", "STATE"); err != nil {
+		return nil, err
+	}
+
+	crosswalk := &ZIPCrosswalk{byZIP: make(map[string][]zipPlace), byCity: make(map[string][]zipPlace)}
+	for {
+		rec, err := table.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		text := rec.text
+		p := zipPlace{ZIP: text("ZIP"), City: text("CITY"), County: text("COUNTY"), CBSA: text("CBSA"), Area: text("AREA")}
+		if p.ZIP == "" {
+			continue
+		}
+		if !zipPattern.MatchString(p.ZIP) {
+			return nil, fmt.Errorf("line %d: invalid ZIP %q", rec.line, p.ZIP)
+		}
+		// HUD marks counties outside a CBSA with 99999
+		if p.CBSA == "99999" {
+			p.CBSA = ""
+		}
+		if _, abbr, ok := lookupState(text("STATE")); ok {
+			p.State = abbr
+		}
+		crosswalk.byZIP[p.ZIP] = append(crosswalk.byZIP[p.ZIP], p)
+		if p.City != "" && p.State != "" {
+			key := cityKey(p.City, p.State)
+			crosswalk.byCity[key] = append(crosswalk.byCity[key], p)
+		}
+	}
+	return crosswalk, nil
+}
+
+// resolveAreas maps crosswalk places to the areas holding career data, most
+// specific first: the metro areas of their CBSAs, then the OEWS areas of
+// their counties (nonmetropolitan areas), then their states
+func (h *Handlers) resolveAreas(ctx context.Context, places []zipPlace) ([]Area, error) {
+	metadata, err := h.store.Areas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	titles, err := h.store.ListAreas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	available := make(map[string]bool, len(titles))
+	for _, t := range titles {
+		available[t] = true
+	}
+	byCode := make(map[string]Area)
+	for _, a := range metadata {
+		if a.Code != "" && available[a.Title] {
+			byCode[a.Code] = a
+		}
+	}
+
+	var areas []Area
+	add := func(a Area, ok bool) {
+		if !ok {
+			return
+		}
+		for _, seen := range areas {
+			if seen.Title == a.Title {
+				return
+			}
+		}
+		areas = append(areas, a)
+	}
+	for _, p := range places {
+		if p.CBSA != "" {
+			a, ok := byCode[p.CBSA]
+			add(a, ok && a.Type == areaTypeMetro)
+		}
+	}
+	for _, p := range places {
+		if p.Area != "" {
+			a, ok := byCode[p.Area]
+			add(a, ok)
+		}
+	}
+	for _, p := range places {
+		if name, _, ok := lookupState(p.State); ok && available[name] {
+			a, known := metadata[name]
+			if !known {
+				a = Area{Title: name, Type: areaTypeState, States: []string{p.State}}
+			}
+			add(a, true)
+		}
+	}
+	return areas, nil
+}
+
+// zipPlaces validates a ZIP code and returns its crosswalk records
+func (c *ZIPCrosswalk) zipPlaces(zip string) ([]zipPlace, error) {
+	if !zipPattern.MatchString(zip) {
+		return nil, requestError("zip must be a five-digit ZIP code")
+	}
+	return c.byZIP[zip], nil
+}
+
+// ResolveAreaHandler handles /api/resolve-area: the areas holding career data
+// for a ZIP code (?zip=) or a city (?city=&state=), most specific first
+func (h *Handlers) ResolveAreaHandler(w http.ResponseWriter, r *http.Request) {
+	if h.zips == nil {
+		http.Error(w, "Area resolution is not available: no ZIP crosswalk is loaded", http.StatusServiceUnavailable)
+		return
+	}
+	q := r.URL.Query()
+	zip, city, state := strings.TrimSpace(q.Get("zip")), strings.TrimSpace(q.Get("city")), q.Get("state")
+
+	var places []zipPlace
+	switch {
+	case zip != "" && city != "":
+		http.Error(w, "zip and city cannot be combined", http.StatusBadRequest)
+		return
+	case zip != "":
+		var err error
+		if places, err = h.zips.zipPlaces(zip); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case city != "":
+		_, abbr, ok := lookupState(state)
+		if !ok {
+			http.Error(w, "city requires a known state", http.StatusBadRequest)
+			return
+		}
+		places = h.zips.byCity[cityKey(city, abbr)]
+	default:
+		http.Error(w, "zip or city and state are required", http.StatusBadRequest)
+		return
+	}
+
+	areas, err := h.resolveAreas(r.Context(), places)
+	if err != nil {
+		log.Printf("Error resolving areas: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"areas": areas,
+		"count": len(areas),
+	}); err != nil {
+		log.Printf("Error encoding response: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// parseZIPFilters reads the /api/calculate filters with zip standing in for
+// location: it selects the most specific area with data for the ZIP code
+func (h *Handlers) parseZIPFilters(r *http.Request, zip string) (Filters, error) {
+	filters, err := parseCriteria(r)
+	if err != nil {
+		return filters, err
+	}
+	if filters.Location != "" || filters.AreaCode != "" {
+		return filters, requestError("zip cannot be combined with location or areaCode")
+	}
+	if h.zips == nil {
+		return filters, requestError("zip is not supported: no ZIP crosswalk is loaded")
+	}
+	places, err := h.zips.zipPlaces(zip)
+	if err != nil {
+		return filters, err
+	}
+	areas, err := h.resolveAreas(r.Context(), places)
+	if err != nil {
+		return filters, err
+	}
+	if len(areas) == 0 {
+		return filters, requestError("no area with career data found for ZIP " + zip)
+	}
+	filters.Location, filters.LocationMatch = areas[0].Title, matchExact
+	return filters, nil
+}
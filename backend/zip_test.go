@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testZIPCrosswalkCSV = `ZIP,COUNTY,CBSA,USPS_ZIP_PREF_CITY,USPS_ZIP_PREF_STATE,AREA
+48201,26163,19820,DETROIT,MI,
+49855,26103,99999,MARQUETTE,MI,2600004
+43604,39095,45780,TOLEDO,OH,
+99501,02020,11260,ANCHORAGE,AK,
+`
+
+// newTestZIPHandlers returns the shared area Handlers with the test crosswalk
+func newTestZIPHandlers(t *testing.T) *Handlers {
+	t.Helper()
+	h := newTestAreaHandlers(t)
+	var err error
+	if h.zips, err = readZIPCrosswalkCSV(strings.NewReader(testZIPCrosswalkCSV)); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestResolveAreaHandler(t *testing.T) {
+	h := newTestZIPHandlers(t)
+	resolve := func(query string) (int, []string) {
+		rr := httptest.NewRecorder()
+		h.ResolveAreaHandler(rr, httptest.NewRequest("GET", "/api/resolve-area?"+query, nil))
+		var body struct {
+			Areas []Area `json:"areas"`
+		}
+		json.NewDecoder(rr.Body).Decode(&body)
+		var titles []string
+		for _, a := range body.Areas {
+			titles = append(titles, a.Title)
+		}
+		return rr.Code, titles
+	}
+
+	for query, want := range map[string]string{
+		"zip=48201":                     "Detroit-Warren-Dearborn, MI|Michigan",
+		"zip=49855":                     "Upper Peninsula of Michigan nonmetropolitan area|Michigan",
+		"zip=43604":                     "Toledo, OH",
+		"zip=99501":                     "",
+		"zip=10001":                     "",
+		"city=Detroit&state=MI":         "Detroit-Warren-Dearborn, MI|Michigan",
+		"city=marquette&state=Michigan": "Upper Peninsula of Michigan nonmetropolitan area|Michigan",
+	} {
+		code, titles := resolve(query)
+		if code != http.StatusOK || strings.Join(titles, "|") != want {
+			t.Errorf("%s: expected %q, got %d %v", query, want, code, titles)
+		}
+	}
+	for _, query := range []string{"", "zip=4820", "zip=48201&city=Detroit", "city=Detroit", "city=Detroit&state=Atlantis"} {
+		if code, _ := resolve(query); code != http.StatusBadRequest {
+			t.Errorf("%q: expected 400, got %d", query, code)
+		}
+	}
+
+	h.zips = nil
+	if code, _ := resolve("zip=48201"); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 without a crosswalk, got %d", code)
+	}
+}
+
+func TestCalculateHandlerAcceptsZIP(t *testing.T) {
+	h := newTestZIPHandlers(t)
+	result := calculate(t, h, "zip=48201&occupation=Registered+Nurses")
+	if result.Location != "Detroit-Warren-Dearborn, MI" || result.MatchingJobs != 40000 || result.AreaCode != "19820" {
+		t.Errorf("expected the Detroit metro, got %+v", result)
+	}
+
+	for _, query := range []string{"zip=99501", "zip=abcde", "zip=48201&location=Michigan"} {
+		rr := httptest.NewRecorder()
+		h.CalculateHandler(rr, httptest.NewRequest("GET", "/api/calculate?"+query, nil))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rr.Code)
+		}
+	}
+}
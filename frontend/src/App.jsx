@@ -18,6 +18,7 @@ function App() {
     const f = {
       location: params.get('location') || '',
       occupation: params.get('occupation') || '',
+      occCode: params.get('occCode') || '',
       minSalary: params.get('minSalary') ? Number(params.get('minSalary')) : 80000,
       education: params.get('education') || 'Any',
       experience: params.get('experience') || 'Any',
@@ -54,7 +54,7 @@ function Filters({ onCalculate, initialValues }) {
   const [minSalary, setMinSalary] = useState(initialValues?.minSalary ?? 80000);
   const [education, setEducation] = useState(initialValues?.education || educationOptions[0]); // Default to "Any"
   const [experience, setExperience] = useState(initialValues?.experience || experienceOptions[0]); // Default to "Any"
-  const [occupations, setOccupations] = useState([]); // State for real occupation data
+  const [occupations, setOccupations] = useState([]); // { code, title } pairs from the backend
   const [isLoadingOccupations, setIsLoadingOccupations] = useState(true); // Loading state
   const [states, setStates] = useState([]); // State list for first dropdown
   const [isLoadingStates, setIsLoadingStates] = useState(true);
@@ -155,9 +155,15 @@ function Filters({ onCalculate, initialValues }) {
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [initialValues]);
 
+  // Titles for the dropdown; codes are looked up on submit
+  const occupationTitles = [...new Set(occupations.map((o) => o.title))];
+
   const handleSubmit = (event) => {
     event.preventDefault(); // Prevent full page reload on form submission
     const payload = { location, occupation, minSalary };
+    // Send the SOC code for a picked title so similar titles aren't mixed in
+    const selected = occupations.filter((o) => o.title === occupation);
+    if (selected.length === 1) payload.occCode = selected[0].code;
     if (education && education !== 'Any') payload.education = education;
     // Only include experience if not "Any"
     if (experience && experience !== 'Any') payload.experience = experience;
@@ -180,7 +186,7 @@ function Filters({ onCalculate, initialValues }) {
         <FilterRow label="Occupation / Field">
           {/* Use our new searchable dropdown component */}
           <SearchableDropdown
-            options={occupations}
+            options={occupationTitles}
             value={occupation}
             onChange={setOccupation}
             placeholder={isLoadingOccupations ? "Loading occupations..." : "e.g., Software Developer"}
@@ -109,6 +109,12 @@ const ResultDisplay = ({ data, shareFilters }) => {
       <span className="font-bold">{data.matchingJobs.toLocaleString()}</span> out of{' '}
       <span className="font-bold">{Number(denom || 0).toLocaleString()}</span> jobs in {view === 'national' ? 'the U.S.' : data.location} meet your standards.
     </p>
+    {data.status === 'noRows' && (
+      <p className="font-secondary text-sm text-gray-400 mt-2">No published data matches this occupation and location.</p>
+    )}
+    {data.status === 'wageSuppressed' && (
+      <p className="font-secondary text-sm text-gray-400 mt-2">Wages for these jobs are not published here, so your salary target could not be checked.</p>
+    )}
     
     {/* Salary Information removed by request */}
     </AnimatedGradientBorder>